@@ -0,0 +1,152 @@
+package skynology
+
+import "strings"
+
+// ACL中表示所有人的key
+const ACL_PUBLIC_KEY = "*"
+
+// ACL 权限检查器
+// 与服务端的权限判断规则一致, 可在私有部署的自定义Handler中使用:
+//   - master 权限跳过所有检查
+//   - 没有ACL的数据对所有人可读写
+//   - `*` 表示所有人
+//   - 用户拥有的角色会按角色继承关系展开
+//   - 格式错误的单个ACL项会被忽略, 而不是让整个检查失败
+//   - 整个ACL格式错误时所有人均无权限
+type ACLChecker struct {
+	UserId string
+	Master bool
+	roles  map[string]bool
+}
+
+// 创建权限检查器
+// roles 为用户直接拥有的角色名, 如通过 User.Roles 得到的结果
+func NewACLChecker(userId string, roles []string, master bool) *ACLChecker {
+	checker := &ACLChecker{
+		UserId: userId,
+		Master: master,
+		roles:  make(map[string]bool),
+	}
+	for _, role := range roles {
+		checker.roles[role] = true
+	}
+	return checker
+}
+
+// 按角色继承关系展开当前拥有的角色
+// 若某角色的子角色已被拥有, 则该角色也被拥有
+func (c *ACLChecker) SetRoleHierarchy(roles []*Role) *ACLChecker {
+	for changed := true; changed; {
+		changed = false
+		for _, role := range roles {
+			if role.Name == "" || c.roles[role.Name] {
+				continue
+			}
+			for _, child := range role.ChildRoles() {
+				if c.roles[child] {
+					c.roles[role.Name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return c
+}
+
+// 返回展开后的全部角色名
+func (c *ACLChecker) Roles() []string {
+	var result []string
+	for role := range c.roles {
+		result = append(result, role)
+	}
+	return result
+}
+
+// 检查给定ACL是否允许指定操作
+// acl 为nil时表示没有设置权限, 所有人均可操作
+func (c *ACLChecker) Check(acl ACL, typ AccessControlType) bool {
+	if c.Master || acl == nil {
+		return true
+	}
+
+	if allowed(acl[ACL_PUBLIC_KEY], typ) {
+		return true
+	}
+	if c.UserId != "" && allowed(acl[c.UserId], typ) {
+		return true
+	}
+	for role := range c.roles {
+		if allowed(acl["role:"+role], typ) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *ACLChecker) CanRead(obj *Object) bool {
+	return c.Check(parseObjectACL(obj), AccessControlTypeRead)
+}
+
+func (c *ACLChecker) CanWrite(obj *Object) bool {
+	return c.Check(parseObjectACL(obj), AccessControlTypeWrite)
+}
+
+// 过滤出有读权限的数据, 如 Query.Find 的结果
+func (c *ACLChecker) FilterReadable(objects []Object) []Object {
+	var result []Object
+	for i := range objects {
+		if c.CanRead(&objects[i]) {
+			result = append(result, objects[i])
+		}
+	}
+	return result
+}
+
+func allowed(item ACLItem, typ AccessControlType) bool {
+	if typ == AccessControlTypeRead {
+		return item.Read
+	}
+	if typ == AccessControlTypeWrite {
+		return item.Write
+	}
+	return false
+}
+
+// 宽松地解析数据中的ACL, 忽略格式错误的项
+// 没有ACL字段时返回 obj.ACL, 字段存在但整体格式错误时返回空ACL, 即所有人均无权限
+func parseObjectACL(obj *Object) ACL {
+	value := obj.Get("ACL")
+	if value == nil {
+		return obj.ACL
+	}
+
+	switch v := value.(type) {
+	case ACL:
+		return v
+	case map[string]interface{}:
+		acl := ACL{}
+		for key, item := range v {
+			itemMap, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			read, _ := itemMap["read"].(bool)
+			write, _ := itemMap["write"].(bool)
+			acl[key] = ACLItem{Read: read, Write: write}
+		}
+		return acl
+	}
+
+	return ACL{}
+}
+
+func parseAccessControlType(typ string) (AccessControlType, bool) {
+	switch strings.ToLower(typ) {
+	case "read":
+		return AccessControlTypeRead, true
+	case "write":
+		return AccessControlTypeWrite, true
+	}
+	return 0, false
+}
@@ -0,0 +1,84 @@
+package skynology_test
+
+import (
+	"testing"
+
+	sky "github.com/skynology/go-sdk"
+)
+
+func objectWithACL(acl interface{}) *sky.Object {
+	data := map[string]interface{}{"objectId": "o1"}
+	if acl != nil {
+		data["ACL"] = acl
+	}
+	return sky.NewApp("app", "key").NewObjectWithData("Post", data)
+}
+
+func TestCheckACL(t *testing.T) {
+	public := map[string]interface{}{"*": map[string]interface{}{"read": true}}
+	owner := map[string]interface{}{
+		"u1":          map[string]interface{}{"read": true, "write": true},
+		"role:editor": map[string]interface{}{"write": true},
+	}
+	partlyMalformed := map[string]interface{}{
+		"u1": map[string]interface{}{"read": true},
+		"u2": "garbage",
+	}
+
+	tests := []struct {
+		name   string
+		acl    interface{}
+		userId string
+		roles  []string
+		typ    string
+		want   bool
+	}{
+		{"no acl", nil, "", nil, "write", true},
+		{"public read", public, "", nil, "read", true},
+		{"public no write", public, "u1", nil, "write", false},
+		{"user read", owner, "u1", nil, "read", true},
+		{"other user", owner, "u2", nil, "read", false},
+		{"role write", owner, "u2", []string{"editor"}, "write", true},
+		{"role no read", owner, "u2", []string{"editor"}, "read", false},
+		{"unknown type", owner, "u1", nil, "delete", false},
+		{"malformed entry skipped", partlyMalformed, "u1", nil, "read", true},
+		{"malformed entry denies", partlyMalformed, "u2", nil, "read", false},
+		{"malformed acl read", "garbage", "u1", nil, "read", false},
+		{"malformed acl write", "garbage", "u1", nil, "write", false},
+		{"malformed acl array", []interface{}{"*"}, "", nil, "read", false},
+	}
+
+	for _, tt := range tests {
+		if got := objectWithACL(tt.acl).CheckACL(tt.userId, tt.roles, tt.typ); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestACLCheckerMasterAndRoleHierarchy(t *testing.T) {
+	app := sky.NewApp("app", "key")
+	obj := objectWithACL(map[string]interface{}{"role:admin": map[string]interface{}{"read": true, "write": true}})
+
+	if !sky.NewACLChecker("", nil, true).CanWrite(obj) {
+		t.Error("master should bypass acl")
+	}
+	if !sky.NewACLChecker("", nil, true).CanRead(objectWithACL("garbage")) {
+		t.Error("master should bypass malformed acl")
+	}
+
+	// editor 属于 moderator, moderator 属于 admin
+	roles := []*sky.Role{
+		app.NewRoleWithData(map[string]interface{}{"name": "admin", "roles": []interface{}{"moderator"}}),
+		app.NewRoleWithData(map[string]interface{}{"name": "moderator", "roles": []interface{}{"editor"}}),
+	}
+	checker := sky.NewACLChecker("u1", []string{"editor"}, false)
+	if checker.CanWrite(obj) {
+		t.Error("editor should not have access before expanding the hierarchy")
+	}
+	if !checker.SetRoleHierarchy(roles).CanWrite(obj) {
+		t.Errorf("editor should inherit admin access, roles %v", checker.Roles())
+	}
+	if sky.NewACLChecker("u1", []string{"viewer"}, false).SetRoleHierarchy(roles).CanRead(obj) {
+		t.Error("unrelated role should not inherit access")
+	}
+}
@@ -0,0 +1,180 @@
+package skynology
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 聚合查询
+// 以 Query 的 where 条件作为 $match, 之后的各个阶段按调用顺序执行
+type Aggregation struct {
+	query  *Query
+	stages []map[string]interface{}
+	group  map[string]interface{}
+	keys   []string
+	err    *APIError
+}
+
+// 按调用顺序排列的排序条件, 保证生成的 JSON 中字段顺序不变
+type sortSpec []string
+
+func (s sortSpec) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, field := range s {
+		direction := 1
+		if strings.HasPrefix(field, "-") {
+			field = field[1:]
+			direction = -1
+		}
+		key, err := json.Marshal(field)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.Write(key)
+		fmt.Fprintf(&buf, ":%d", direction)
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+// 创建聚合查询
+func (query *Query) Aggregate() *Aggregation {
+	agg := &Aggregation{query: query, err: query.err}
+	if len(query.where) > 0 {
+		agg.stages = append(agg.stages, map[string]interface{}{"$match": copyValue(query.where)})
+	}
+	return agg
+}
+
+// 按字段分组, 不传字段时所有数据为一组
+// 分组字段会出现在结果的每一行中
+func (agg *Aggregation) GroupBy(fields ...string) *Aggregation {
+	var id interface{}
+	if len(fields) > 0 {
+		m := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			m[field] = "$" + field
+		}
+		id = m
+	}
+
+	agg.group = map[string]interface{}{"_id": id}
+	agg.keys = fields
+	agg.stages = append(agg.stages, map[string]interface{}{"$group": agg.group})
+	return agg
+}
+
+func (agg *Aggregation) Sum(alias string, field string) *Aggregation {
+	return agg.accumulate(alias, "$sum", "$"+field)
+}
+
+func (agg *Aggregation) Avg(alias string, field string) *Aggregation {
+	return agg.accumulate(alias, "$avg", "$"+field)
+}
+
+func (agg *Aggregation) Min(alias string, field string) *Aggregation {
+	return agg.accumulate(alias, "$min", "$"+field)
+}
+
+func (agg *Aggregation) Max(alias string, field string) *Aggregation {
+	return agg.accumulate(alias, "$max", "$"+field)
+}
+
+// 每组的数量
+func (agg *Aggregation) Count(alias string) *Aggregation {
+	return agg.accumulate(alias, "$sum", 1)
+}
+
+// 排序, 字段前加 `-` 表示倒序, 与 OrderByDescending 一致
+func (agg *Aggregation) Sort(fields ...string) *Aggregation {
+	agg.stages = append(agg.stages, map[string]interface{}{"$sort": sortSpec(fields)})
+	return agg
+}
+
+func (agg *Aggregation) Limit(value int) *Aggregation {
+	agg.stages = append(agg.stages, map[string]interface{}{"$limit": value})
+	return agg
+}
+
+// 只返回指定字段
+func (agg *Aggregation) Project(fields ...string) *Aggregation {
+	project := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		project[field] = 1
+	}
+	agg.stages = append(agg.stages, map[string]interface{}{"$project": project})
+	return agg
+}
+
+// 执行聚合, 返回每组一行数据
+func (agg *Aggregation) Find() ([]map[string]interface{}, *APIError) {
+	var rows []map[string]interface{}
+	if agg.err != nil {
+		return nil, agg.err
+	}
+
+	url := fmt.Sprintf("%s/aggregate/%s", agg.query.app.baseURL, agg.query.ResourceName)
+	m, err := agg.query.app.sendPostRequest(url, map[string]interface{}{"pipeline": agg.stages})
+	if err != nil {
+		return nil, err
+	}
+
+	if results, ok := m["results"].([]interface{}); ok {
+		for _, r := range results {
+			if row, ok := r.(map[string]interface{}); ok {
+				rows = append(rows, agg.flattenGroupKey(row))
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+// 执行聚合并将结果解析到 result 中, result 需为 slice 指针, 如 *[]struct{...}
+func (agg *Aggregation) FindInto(result interface{}) *APIError {
+	rows, err := agg.Find()
+	if err != nil {
+		return err
+	}
+
+	b, e := json.Marshal(rows)
+	if e != nil {
+		return &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("marshal aggregate results error:%v", e.Error())}
+	}
+	if e = json.Unmarshal(b, result); e != nil {
+		return &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("decode aggregate results error:%v", e.Error())}
+	}
+	return nil
+}
+
+func (agg *Aggregation) accumulate(alias string, op string, value interface{}) *Aggregation {
+	if agg.group == nil {
+		agg.GroupBy()
+	}
+	if alias == "_id" {
+		agg.err = &APIError{Code: ERROR_CODE_CLIENT, Error: "aggregate alias cannot be '_id'"}
+		return agg
+	}
+	agg.group[alias] = map[string]interface{}{op: value}
+	return agg
+}
+
+// 将分组的 _id 展开到行中
+func (agg *Aggregation) flattenGroupKey(row map[string]interface{}) map[string]interface{} {
+	id, ok := row["_id"].(map[string]interface{})
+	if !ok {
+		return row
+	}
+	for _, key := range agg.keys {
+		if _, exists := row[key]; !exists {
+			row[key] = id[key]
+		}
+	}
+	return row
+}
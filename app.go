@@ -1,6 +1,12 @@
 package skynology
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
 
 // create a new Skynology sdk instance
 func NewApp(appId, appKey string) *App {
@@ -11,6 +17,8 @@ func NewApp(appId, appKey string) *App {
 		dataDir:        "./",
 		weixinParams:   new(weixinParams),
 		handler:        NewDefaultHandler(),
+		cache:          NewMemoryCache(),
+		maxURLLength:   DEFAULT_MAX_URL_LENGTH,
 	}
 }
 
@@ -23,6 +31,8 @@ func NewAppWithMasterKey(appId, masterKey string) *App {
 		dataDir:       "./",
 		weixinParams:  new(weixinParams),
 		handler:       NewDefaultHandler(),
+		cache:         NewMemoryCache(),
+		maxURLLength:  DEFAULT_MAX_URL_LENGTH,
 	}
 }
 
@@ -70,9 +80,99 @@ func (app *App) NewQuery(resourceName string) *Query {
 		ResourceName: resourceName,
 		_take:        20,
 		where:        make(map[string]interface{}),
+		debug:        new(queryDebugRecorder),
 	}
 }
 
+// 从查询字符串还原查询, 如 Query.String 的结果
+func (app *App) ParseQuery(resourceName string, values url.Values) (*Query, *APIError) {
+	query := app.NewQuery(resourceName)
+
+	if v := values.Get("count"); v != "" {
+		query._count = v == "1" || v == "true"
+	}
+	if v := values.Get("highlight"); v != "" {
+		query.highlight = v == "1" || v == "true"
+	}
+	if v := values.Get("order"); v != "" {
+		query.order = strings.Split(v, ",")
+	}
+	if v := values.Get("select"); v != "" {
+		query.field = strings.Split(v, ",")
+	}
+	if v := values.Get("include"); v != "" {
+		query.include = strings.Split(v, ",")
+	}
+	if v := values.Get("skip"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("invalid skip value '%s'", v)}
+		}
+		query._skip = n
+	}
+	if v := values.Get("take"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("invalid take value '%s'", v)}
+		}
+		query._take = n
+	}
+	if v := values.Get("where"); v != "" {
+		decoder := json.NewDecoder(strings.NewReader(v))
+		decoder.UseNumber()
+		if err := decoder.Decode(&query.where); err != nil {
+			return nil, &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("invalid where value:%v", err.Error())}
+		}
+		if query.where == nil {
+			query.where = make(map[string]interface{})
+		}
+	}
+
+	return query, nil
+}
+
+// 组合多个查询, 满足任意一个查询条件即可 ($or)
+// 返回的查询可继续设置 skip/take/order/include 等
+func (app *App) OrQuery(queries ...*Query) *Query {
+	return app.compoundQuery("$or", queries)
+}
+
+// 组合多个查询, 需同时满足所有查询条件 ($and)
+func (app *App) AndQuery(queries ...*Query) *Query {
+	return app.compoundQuery("$and", queries)
+}
+
+// 组合多个查询, 不满足任何一个查询条件 ($nor)
+func (app *App) NorQuery(queries ...*Query) *Query {
+	return app.compoundQuery("$nor", queries)
+}
+
+func (app *App) compoundQuery(op string, queries []*Query) *Query {
+	if len(queries) == 0 {
+		query := app.NewQuery("")
+		query.err = &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("%s query needs at least one sub query", op)}
+		return query
+	}
+
+	query := app.NewQuery(queries[0].ResourceName)
+	conditions := make([]interface{}, 0, len(queries))
+	for _, q := range queries {
+		if q.err != nil {
+			query.err = q.err
+			return query
+		}
+		if q.ResourceName != query.ResourceName {
+			query.err = &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("%s sub queries must have the same resource name, got '%s' and '%s'", op, query.ResourceName, q.ResourceName)}
+			return query
+		}
+		// 复制条件, 之后修改子查询不影响组合查询
+		conditions = append(conditions, copyValue(q.where))
+	}
+	query.where[op] = conditions
+
+	return query
+}
+
 // create new user
 func (app *App) NewUser() *User {
 	user := &User{
@@ -119,6 +219,54 @@ func (app *App) NewUserWithData(data map[string]interface{}) *User {
 	return user
 }
 
+// create new role with name
+func (app *App) NewRole(name string) *Role {
+	role := &Role{
+		Object: Object{
+			app:          app,
+			ResourceName: "_Role",
+			data:         make(map[string]interface{}),
+			changedData:  make(map[string]interface{}),
+			baseURL:      fmt.Sprintf("%s/resources/_Role", app.baseURL),
+		},
+	}
+	role.Name = name
+	role.Set("name", name)
+
+	return role
+}
+
+func (app *App) NewRoleWithId(objectId string) *Role {
+	role := &Role{
+		Object: Object{
+			app:          app,
+			ResourceName: "_Role",
+			data:         make(map[string]interface{}),
+			changedData:  make(map[string]interface{}),
+			baseURL:      fmt.Sprintf("%s/resources/_Role", app.baseURL),
+		},
+	}
+	role.ObjectId = objectId
+
+	return role
+}
+
+func (app *App) NewRoleWithData(data map[string]interface{}) *Role {
+	role := &Role{
+		Object: Object{
+			app:          app,
+			ResourceName: "_Role",
+			data:         make(map[string]interface{}),
+			changedData:  make(map[string]interface{}),
+			baseURL:      fmt.Sprintf("%s/resources/_Role", app.baseURL),
+		},
+	}
+
+	role.initData(data)
+
+	return role
+}
+
 // get logined user
 func (app *App) CurrentUser() *User {
 	user, err := app.getUserFromDisk()
@@ -0,0 +1,49 @@
+package skynology
+
+import "fmt"
+
+// 批量请求中的单个请求
+// Path 不包含通用部分, 如 "resources/Post" 或 "resources/Post/<objectId>"
+type batchRequest struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Body   interface{} `json:"body,omitempty"`
+}
+
+// 批量请求中单个请求的结果
+type batchResult struct {
+	Success map[string]interface{}
+	Error   *APIError
+}
+
+// 发送批量请求, 结果与请求一一对应
+func (app *App) sendBatch(requests []batchRequest) ([]batchResult, *APIError) {
+	url := fmt.Sprintf("%s/batch", app.baseURL)
+	m, err := app.sendPostRequest(url, map[string]interface{}{"requests": requests})
+	if err != nil {
+		return nil, err
+	}
+
+	items, _ := m["results"].([]interface{})
+	if len(items) != len(requests) {
+		return nil, &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("batch returned %d results for %d requests", len(items), len(requests))}
+	}
+
+	results := make([]batchResult, len(items))
+	for i, item := range items {
+		itemMap, _ := item.(map[string]interface{})
+		if e, ok := itemMap["error"].(map[string]interface{}); ok {
+			results[i].Error = &APIError{
+				Code:        GetInt(e["code"]),
+				Error:       GetString(e["error"]),
+				EnError:     GetString(e["error_en"]),
+				Description: GetString(e["description"]),
+			}
+			continue
+		}
+		success, _ := itemMap["success"].(map[string]interface{})
+		results[i].Success = success
+	}
+
+	return results, nil
+}
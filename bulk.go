@@ -0,0 +1,138 @@
+package skynology
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// 服务器不支持按条件批量操作时, 每批处理的数量
+const bulkBatchSize = 50
+
+// 允许没有 where 条件时执行 UpdateAll 及 DeleteAll, 即操作该资源的所有数据
+func (query *Query) AllowEmptyWhere() *Query {
+	query.allowEmptyWhere = true
+	return query
+}
+
+// 按 __op 形式的修改内容更新所有匹配 where 条件的数据, 返回更新的数量
+// 没有 where 条件时需先调用 AllowEmptyWhere, 服务器不支持时会分页查询并批量更新
+func (query *Query) UpdateAll(changes map[string]interface{}) (int, *APIError) {
+	search, err := query.bulkQueryString()
+	if err != nil {
+		return 0, err
+	}
+
+	url := fmt.Sprintf("%s/bulk/resources/%s?%s", query.app.baseURL, query.ResourceName, search)
+	m, err := query.app.sendPutRequest(url, changes)
+	if err != nil && isEndpointMissing(err) {
+		return query.bulkFallback("PUT", changes)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	query.app.invalidateCache(query.ResourceName)
+	return GetInt(m["count"]), nil
+}
+
+// 删除所有匹配 where 条件的数据, 返回删除的数量
+// 没有 where 条件时需先调用 AllowEmptyWhere, 服务器不支持时会分页查询并批量删除
+func (query *Query) DeleteAll() (int, *APIError) {
+	search, err := query.bulkQueryString()
+	if err != nil {
+		return 0, err
+	}
+
+	url := fmt.Sprintf("%s/bulk/resources/%s?%s", query.app.baseURL, query.ResourceName, search)
+	m, err := query.app.sendDeleteRequest(url, nil)
+	if err != nil && isEndpointMissing(err) {
+		return query.bulkFallback("DELETE", nil)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	query.app.invalidateCache(query.ResourceName)
+	return GetInt(m["count"]), nil
+}
+
+// 检查查询并返回查询字符串, where 为空或无法编码时不执行, 避免操作所有数据
+func (query *Query) bulkQueryString() (string, *APIError) {
+	if query.err != nil {
+		return "", query.err
+	}
+	if len(query.where) == 0 && !query.allowEmptyWhere {
+		return "", &APIError{Code: ERROR_CODE_CLIENT, Error: "where is empty, call AllowEmptyWhere to update or delete all data"}
+	}
+	where, err := query.encodedWhere()
+	if err != nil {
+		return "", err
+	}
+	return "_=_&where=" + where, nil
+}
+
+// 按游标分页查询, 每批数据通过批量请求更新或删除
+// 游标分页不受已删除数据的影响
+func (query *Query) bulkFallback(method string, changes map[string]interface{}) (int, *APIError) {
+	count := 0
+	var requests []batchRequest
+
+	flush := func() *APIError {
+		if len(requests) == 0 {
+			return nil
+		}
+		results, err := query.app.sendBatch(requests)
+		requests = nil
+		if err != nil {
+			return err
+		}
+		// 部分失败时其余请求已执行, 先计数再返回第一个错误
+		var first *APIError
+		for _, result := range results {
+			if result.Error != nil {
+				if first == nil {
+					first = result.Error
+				}
+				continue
+			}
+			count++
+		}
+		return first
+	}
+
+	q := query.clone()
+	q.field = []string{"objectId"}
+	q.include = nil
+
+	var flushErr *APIError
+	err := q.Iterator().PageSize(bulkBatchSize).Each(func(obj *Object) error {
+		path := fmt.Sprintf("resources/%s/%s", query.ResourceName, obj.ObjectId)
+		requests = append(requests, batchRequest{Method: method, Path: path, Body: changes})
+		if len(requests) < bulkBatchSize {
+			return nil
+		}
+		if flushErr = flush(); flushErr != nil {
+			return ErrStopIteration
+		}
+		return nil
+	})
+	if err == nil {
+		err = flushErr
+	}
+	if err == nil {
+		err = flush()
+	}
+
+	if count > 0 {
+		query.app.invalidateCache(query.ResourceName)
+	}
+	return count, err
+}
+
+// 服务器是否不支持该接口, 即返回了没有错误信息的 404 或 405
+func isEndpointMissing(err *APIError) bool {
+	if err.Code != ERROR_CODE_CLIENT {
+		return false
+	}
+	return err.StatusCode == http.StatusNotFound || err.StatusCode == http.StatusMethodNotAllowed
+}
@@ -0,0 +1,62 @@
+package skynology_test
+
+import (
+	"math"
+	"testing"
+
+	sky "github.com/skynology/go-sdk"
+)
+
+// 记录请求的 handler, 返回固定结果
+type recordingHandler struct {
+	requests []sky.HandlerRequestParams
+	response map[string]interface{}
+}
+
+func (h *recordingHandler) SendRequest(params sky.HandlerRequestParams) (map[string]interface{}, *sky.APIError) {
+	h.requests = append(h.requests, params)
+	return h.response, nil
+}
+
+func TestBulkRefusesUnfilteredQueries(t *testing.T) {
+	tests := []struct {
+		name  string
+		query func(app *sky.App) *sky.Query
+	}{
+		{"empty where", func(app *sky.App) *sky.Query { return app.NewQuery("Post") }},
+		{"unencodable where", func(app *sky.App) *sky.Query { return app.NewQuery("Post").LessThan("score", math.NaN()) }},
+		{"unencodable where with AllowEmptyWhere", func(app *sky.App) *sky.Query {
+			return app.NewQuery("Post").LessThan("score", math.NaN()).AllowEmptyWhere()
+		}},
+	}
+
+	for _, tt := range tests {
+		handler := &recordingHandler{response: map[string]interface{}{"count": 1.0}}
+		app := sky.NewApp("app", "key")
+		app.SetRequestHandler(handler)
+
+		if _, err := tt.query(app).DeleteAll(); err == nil {
+			t.Errorf("%s: DeleteAll should fail", tt.name)
+		}
+		if _, err := tt.query(app).UpdateAll(map[string]interface{}{"score": 1}); err == nil {
+			t.Errorf("%s: UpdateAll should fail", tt.name)
+		}
+		if len(handler.requests) != 0 {
+			t.Errorf("%s: sent %d requests, want none", tt.name, len(handler.requests))
+		}
+	}
+}
+
+func TestBulkAllowEmptyWhere(t *testing.T) {
+	handler := &recordingHandler{response: map[string]interface{}{"count": 3.0}}
+	app := sky.NewApp("app", "key")
+	app.SetRequestHandler(handler)
+
+	count, err := app.NewQuery("Post").AllowEmptyWhere().DeleteAll()
+	if err != nil || count != 3 {
+		t.Fatalf("DeleteAll got %d %v", count, err)
+	}
+	if len(handler.requests) != 1 || handler.requests[0].Method != "DELETE" {
+		t.Fatalf("unexpected requests %+v", handler.requests)
+	}
+}
@@ -0,0 +1,268 @@
+package skynology
+
+import (
+	"sync"
+	"time"
+)
+
+// 查询缓存策略
+type CachePolicy int
+
+const (
+	// 只从服务器获取, 默认策略
+	CachePolicyNetworkOnly CachePolicy = iota
+	// 只从缓存获取, 没有缓存时返回错误
+	CachePolicyCacheOnly
+	// 优先从缓存获取, 没有缓存时从服务器获取
+	CachePolicyCacheElseNetwork
+	// 优先从服务器获取, 出错时从缓存获取
+	CachePolicyNetworkElseCache
+	// 有缓存时先返回缓存, 同时在后台从服务器获取,
+	// 结果通过 OnNetworkResult 设置的回调返回并更新缓存
+	CachePolicyCacheThenNetwork
+)
+
+// 查询缓存
+// key 为编码后的查询url, 保存或删除同一资源的数据时会调用 Invalidate
+type QueryCache interface {
+	Get(resourceName string, key string) (map[string]interface{}, bool)
+	Set(resourceName string, key string, value map[string]interface{}, ttl time.Duration)
+	Invalidate(resourceName string)
+}
+
+// 内存缓存默认最多保存的查询结果数量
+const DEFAULT_MEMORY_CACHE_SIZE = 1000
+
+// 默认的内存缓存
+// 超过最大数量时先清除过期的结果, 仍然超过时清除最早保存的结果
+type MemoryCache struct {
+	mu         sync.Mutex
+	items      map[string]map[string]memoryCacheItem
+	size       int
+	maxEntries int
+}
+
+type memoryCacheItem struct {
+	value     map[string]interface{}
+	createdAt time.Time
+	expiresAt time.Time
+}
+
+func NewMemoryCache() *MemoryCache {
+	return NewMemoryCacheWithSize(DEFAULT_MEMORY_CACHE_SIZE)
+}
+
+// 创建指定最大数量的内存缓存, 小于等于0时使用默认数量
+func NewMemoryCacheWithSize(maxEntries int) *MemoryCache {
+	if maxEntries <= 0 {
+		maxEntries = DEFAULT_MEMORY_CACHE_SIZE
+	}
+	return &MemoryCache{items: make(map[string]map[string]memoryCacheItem), maxEntries: maxEntries}
+}
+
+func (c *MemoryCache) Get(resourceName string, key string) (map[string]interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.items[resourceName][key]
+	if !ok {
+		return nil, false
+	}
+	if item.expired(time.Now()) {
+		c.remove(resourceName, key)
+		return nil, false
+	}
+	return item.value, true
+}
+
+// ttl 小于等于0时不过期
+func (c *MemoryCache) Set(resourceName string, key string, value map[string]interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	item := memoryCacheItem{value: value, createdAt: now}
+	if ttl > 0 {
+		item.expiresAt = now.Add(ttl)
+	}
+
+	if _, ok := c.items[resourceName][key]; !ok {
+		if c.size >= c.maxEntries {
+			c.evict(now)
+		}
+		c.size++
+	}
+	if c.items[resourceName] == nil {
+		c.items[resourceName] = make(map[string]memoryCacheItem)
+	}
+	c.items[resourceName][key] = item
+}
+
+func (c *MemoryCache) Invalidate(resourceName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.size -= len(c.items[resourceName])
+	delete(c.items, resourceName)
+}
+
+// 清除过期的结果, 没有过期的结果时清除最早保存的一个
+func (c *MemoryCache) evict(now time.Time) {
+	var oldestResource, oldestKey string
+	var oldest time.Time
+	removed := false
+	for resourceName, items := range c.items {
+		for key, item := range items {
+			if item.expired(now) {
+				c.remove(resourceName, key)
+				removed = true
+				continue
+			}
+			if oldest.IsZero() || item.createdAt.Before(oldest) {
+				oldestResource, oldestKey, oldest = resourceName, key, item.createdAt
+			}
+		}
+	}
+	if !removed && !oldest.IsZero() {
+		c.remove(oldestResource, oldestKey)
+	}
+}
+
+func (c *MemoryCache) remove(resourceName string, key string) {
+	if _, ok := c.items[resourceName][key]; !ok {
+		return
+	}
+	delete(c.items[resourceName], key)
+	if len(c.items[resourceName]) == 0 {
+		delete(c.items, resourceName)
+	}
+	c.size--
+}
+
+func (item memoryCacheItem) expired(now time.Time) bool {
+	return !item.expiresAt.IsZero() && now.After(item.expiresAt)
+}
+
+// 设置查询缓存, 传入nil时关闭缓存
+func (app *App) SetQueryCache(cache QueryCache) {
+	app.cache = cache
+}
+
+func (app *App) invalidateCache(resourceName string) {
+	app.cacheMu.Lock()
+	defer app.cacheMu.Unlock()
+
+	if app.cacheGenerations == nil {
+		app.cacheGenerations = make(map[string]uint64)
+	}
+	app.cacheGenerations[resourceName]++
+	if app.cache != nil {
+		app.cache.Invalidate(resourceName)
+	}
+}
+
+func (app *App) cacheGeneration(resourceName string) uint64 {
+	app.cacheMu.Lock()
+	defer app.cacheMu.Unlock()
+	return app.cacheGenerations[resourceName]
+}
+
+// 请求期间资源的缓存被清除过时不写入, 避免写回修改前的数据
+func (app *App) setCache(resourceName string, key string, value map[string]interface{}, ttl time.Duration, generation uint64) {
+	app.cacheMu.Lock()
+	defer app.cacheMu.Unlock()
+
+	if app.cache != nil && app.cacheGenerations[resourceName] == generation {
+		app.cache.Set(resourceName, key, value, ttl)
+	}
+}
+
+// 设置查询的缓存策略及缓存有效期
+func (query *Query) SetCachePolicy(policy CachePolicy, ttl time.Duration) *Query {
+	query.cachePolicy = policy
+	query.cacheTTL = ttl
+	return query
+}
+
+// 设置 CachePolicyCacheThenNetwork 时从服务器获取到结果后的回调
+// 只在先返回了缓存时调用, 回调在后台协程中执行, GetObject 时 objects 只包含该条数据
+func (query *Query) OnNetworkResult(fn func(objects []Object, count int, err *APIError)) *Query {
+	query.networkResult = fn
+	return query
+}
+
+// 按缓存策略发送查询请求
+func (query *Query) get(url string, objectId string) (map[string]interface{}, *APIError) {
+	cache := query.app.cache
+	if cache == nil || query.cachePolicy == CachePolicyNetworkOnly {
+		return query.request(url, objectId)
+	}
+
+	// 不同用户的权限不同, 缓存需按用户区分
+	key := url
+	if query.app.SessionToken != "" {
+		key += "#" + query.app.SessionToken
+	}
+
+	// 返回副本, 避免修改返回的数据影响缓存
+	cached := func() (map[string]interface{}, bool) {
+		m, ok := cache.Get(query.ResourceName, key)
+		if !ok {
+			return nil, false
+		}
+		query.recordDebug(HandlerRequestParams{Method: "GET", URL: url}, 0, true, nil)
+		return copyValue(m).(map[string]interface{}), true
+	}
+
+	// generation 需在发出请求前获取
+	fetch := func(generation uint64) (map[string]interface{}, *APIError) {
+		m, err := query.request(url, objectId)
+		if err == nil {
+			query.app.setCache(query.ResourceName, key, copyValue(m).(map[string]interface{}), query.cacheTTL, generation)
+		}
+		return m, err
+	}
+
+	switch query.cachePolicy {
+	case CachePolicyCacheOnly:
+		if m, ok := cached(); ok {
+			return m, nil
+		}
+		return nil, &APIError{Code: ERROR_CODE_CACHE_MISS, Error: "no cached result for query"}
+	case CachePolicyCacheElseNetwork:
+		if m, ok := cached(); ok {
+			return m, nil
+		}
+		return fetch(query.app.cacheGeneration(query.ResourceName))
+	case CachePolicyNetworkElseCache:
+		m, err := fetch(query.app.cacheGeneration(query.ResourceName))
+		if err != nil {
+			if c, ok := cached(); ok {
+				return c, nil
+			}
+		}
+		return m, err
+	case CachePolicyCacheThenNetwork:
+		if m, ok := cached(); ok {
+			generation := query.app.cacheGeneration(query.ResourceName)
+			go func() {
+				fresh, err := fetch(generation)
+				if query.networkResult == nil {
+					return
+				}
+				if err != nil {
+					query.networkResult(nil, 0, err)
+				} else if objectId != "" {
+					query.networkResult([]Object{*query.app.NewObjectWithData(query.ResourceName, fresh)}, 1, nil)
+				} else {
+					objects, count := query.parseResults(fresh)
+					query.networkResult(objects, count, nil)
+				}
+			}()
+			return m, nil
+		}
+		return fetch(query.app.cacheGeneration(query.ResourceName))
+	}
+
+	return query.request(url, objectId)
+}
@@ -0,0 +1,111 @@
+package skynology_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	sky "github.com/skynology/go-sdk"
+)
+
+// 第二次查询会等待 gate 关闭后才返回
+type gatedHandler struct {
+	mu   sync.Mutex
+	gets int
+	gate chan struct{}
+}
+
+func (h *gatedHandler) SendRequest(params sky.HandlerRequestParams) (map[string]interface{}, *sky.APIError) {
+	if params.Method != "GET" {
+		return map[string]interface{}{}, nil
+	}
+
+	h.mu.Lock()
+	h.gets++
+	n := h.gets
+	h.mu.Unlock()
+	if n == 2 {
+		<-h.gate
+	}
+	return map[string]interface{}{"results": []interface{}{map[string]interface{}{"objectId": "o1"}}}, nil
+}
+
+func TestCacheThenNetworkSkipsStaleWrite(t *testing.T) {
+	handler := &gatedHandler{gate: make(chan struct{})}
+	app := sky.NewApp("app", "key")
+	app.SetRequestHandler(handler)
+
+	results := make(chan int, 1)
+	query := app.NewQuery("Post").SetCachePolicy(sky.CachePolicyCacheThenNetwork, time.Minute)
+	query.OnNetworkResult(func(objects []sky.Object, count int, err *sky.APIError) {
+		if err != nil {
+			t.Errorf("network result error: %v", err.String())
+		}
+		results <- len(objects)
+	})
+
+	// 第一次没有缓存, 直接从服务器获取
+	if objects, _, err := query.Find(); err != nil || len(objects) != 1 {
+		t.Fatalf("first find got %d objects, %v", len(objects), err)
+	}
+	// 第二次返回缓存, 后台请求等待 gate
+	if objects, _, err := query.Find(); err != nil || len(objects) != 1 {
+		t.Fatalf("cached find got %d objects, %v", len(objects), err)
+	}
+
+	// 后台请求完成前删除数据, 之后返回的旧数据不应写入缓存
+	if _, err := app.NewObjectWithId("Post", "o1").Delete(); err != nil {
+		t.Fatalf("delete error: %v", err.String())
+	}
+	close(handler.gate)
+
+	select {
+	case n := <-results:
+		if n != 1 {
+			t.Errorf("network result got %d objects, want 1", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("network result was not delivered")
+	}
+
+	_, _, err := app.NewQuery("Post").SetCachePolicy(sky.CachePolicyCacheOnly, 0).Find()
+	if err == nil || err.Code != sky.ERROR_CODE_CACHE_MISS {
+		t.Errorf("stale result was written back to the cache, err %v", err)
+	}
+}
+
+func TestMemoryCacheBounded(t *testing.T) {
+	value := map[string]interface{}{"results": []interface{}{}}
+	has := func(cache *sky.MemoryCache, key string) bool {
+		_, ok := cache.Get("Post", key)
+		return ok
+	}
+
+	// 超过数量时清除最早保存的结果
+	cache := sky.NewMemoryCacheWithSize(2)
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Set("Post", key, value, 0)
+		time.Sleep(time.Millisecond)
+	}
+	if has(cache, "a") || !has(cache, "b") || !has(cache, "c") {
+		t.Error("oldest entry was not evicted")
+	}
+
+	// 有过期的结果时先清除过期的
+	cache = sky.NewMemoryCacheWithSize(2)
+	cache.Set("Post", "a", value, 0)
+	cache.Set("Post", "b", value, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	cache.Set("Post", "c", value, 0)
+	if !has(cache, "a") || has(cache, "b") || !has(cache, "c") {
+		t.Error("expired entry was not removed first")
+	}
+
+	// 清除资源后不再占用数量
+	cache.Invalidate("Post")
+	cache.Set("User", "a", value, 0)
+	cache.Set("User", "b", value, 0)
+	if _, ok := cache.Get("User", "a"); !ok {
+		t.Error("invalidated entries still count toward the limit")
+	}
+}
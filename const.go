@@ -20,3 +20,10 @@ const (
 const (
 	SDK_VERSION = "0.1.0"
 )
+
+// SDK 本地产生的错误码
+const (
+	ERROR_CODE_CLIENT           = -1
+	ERROR_CODE_OBJECT_NOT_FOUND = -2
+	ERROR_CODE_CACHE_MISS       = -3
+)
@@ -0,0 +1,113 @@
+package skynology
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// 查询最后一次执行的调试信息
+type QueryDebugInfo struct {
+	Method string
+	URL    string
+	// 解码后的 where 条件
+	Where map[string]interface{}
+	// DefaultHandler 发送的请求头, 签名及 SessionToken 已隐藏
+	Headers   map[string]string
+	Duration  time.Duration
+	FromCache bool
+	Error     *APIError
+}
+
+// 调试信息记录, 查询及其内部复制的查询共用
+type queryDebugRecorder struct {
+	mu   sync.Mutex
+	last *QueryDebugInfo
+}
+
+// 返回查询最后一次执行的调试信息, 还没有执行时返回nil
+func (query *Query) Debug() *QueryDebugInfo {
+	if query.debug == nil {
+		return nil
+	}
+
+	query.debug.mu.Lock()
+	defer query.debug.mu.Unlock()
+	if query.debug.last == nil {
+		return nil
+	}
+	info := *query.debug.last
+	return &info
+}
+
+// 请求服务器返回查询计划及使用的索引
+func (query *Query) Explain() (map[string]interface{}, *APIError) {
+	if query.err != nil {
+		return nil, query.err
+	}
+
+	search, err := query.getQueryString()
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/resources/%s?%s&explain=1", query.app.baseURL, query.ResourceName, search)
+	return query.send("GET", url, nil)
+}
+
+// 发送请求并记录调试信息
+func (query *Query) send(method string, url string, data interface{}) (map[string]interface{}, *APIError) {
+	params, e := query.app.requestParams(method, url, data)
+	if e != nil {
+		err := &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("marshal json data error:%v", e.Error())}
+		query.recordDebug(params, 0, false, err)
+		return nil, err
+	}
+
+	start := time.Now()
+	m, err := query.app.handler.SendRequest(params)
+	query.recordDebug(params, time.Since(start), false, err)
+	return m, err
+}
+
+// 记录发送给 handler 的请求, 从缓存返回时没有请求头
+func (query *Query) recordDebug(params HandlerRequestParams, duration time.Duration, fromCache bool, err *APIError) {
+	if query.debug == nil {
+		return
+	}
+
+	info := &QueryDebugInfo{
+		Method:    params.Method,
+		URL:       params.URL,
+		Duration:  duration,
+		FromCache: fromCache,
+		Error:     err,
+	}
+
+	// GET 查询的 where 在url中, POST 查询的在请求内容中
+	if u, e := url.Parse(params.URL); e == nil {
+		if where := u.Query().Get("where"); where != "" {
+			json.Unmarshal([]byte(where), &info.Where)
+		}
+	}
+	if body, ok := params.Data.(map[string]interface{}); ok && info.Where == nil {
+		// 与url中的 where 一样按JSON解码
+		if b, e := json.Marshal(body["where"]); e == nil {
+			json.Unmarshal(b, &info.Where)
+		}
+	}
+
+	if !fromCache {
+		info.Headers = requestHeaders(params)
+		for _, key := range []string{X_REQUEST_SIGN_HEADER, X_SESSION_TOKEN_HEADER} {
+			if _, ok := info.Headers[key]; ok {
+				info.Headers[key] = "[REDACTED]"
+			}
+		}
+	}
+
+	query.debug.mu.Lock()
+	query.debug.last = info
+	query.debug.mu.Unlock()
+}
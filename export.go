@@ -0,0 +1,172 @@
+package skynology
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 导出格式
+type ExportFormat string
+
+const (
+	ExportFormatJSONL ExportFormat = "jsonl"
+	ExportFormatCSV   ExportFormat = "csv"
+)
+
+// 导出时每页数量
+const exportPageSize = 500
+
+// 导出进度, 可序列化保存下来, 中断后通过 ExportWithCheckpoint 继续导出
+type ExportCheckpoint struct {
+	Cursor  QueryCursor `json:"cursor"`
+	Columns []string    `json:"columns,omitempty"`
+	Count   int         `json:"count"`
+}
+
+// 导出所有匹配的数据, 自动分页
+// CSV 格式的列为 Query.Select 的字段, 未设置时使用第一条数据的字段
+// 嵌套的map会展开为 `a.b` 形式的列, 选择的字段为map时按第一条数据展开为其下的所有列
+func (app *App) Export(query *Query, w io.Writer, format ExportFormat) (*ExportCheckpoint, *APIError) {
+	checkpoint := &ExportCheckpoint{}
+	err := app.ExportWithCheckpoint(query, w, format, checkpoint)
+	return checkpoint, err
+}
+
+// 从 checkpoint 之后继续导出
+// 导出过程中 checkpoint 会随写入的数据更新, 出错时可保存下来用于下次继续
+func (app *App) ExportWithCheckpoint(query *Query, w io.Writer, format ExportFormat, checkpoint *ExportCheckpoint) *APIError {
+	q := query.clone()
+	q.app = app
+	it := q.Iterator().PageSize(exportPageSize).StartAfter(checkpoint.Cursor)
+
+	switch format {
+	case ExportFormatJSONL:
+		return exportJSONL(it, w, checkpoint)
+	case ExportFormatCSV:
+		return exportCSV(it, w, checkpoint, query.field)
+	}
+
+	return &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("unsupported export format '%s'", format)}
+}
+
+func exportJSONL(it *QueryIterator, w io.Writer, checkpoint *ExportCheckpoint) *APIError {
+	encoder := json.NewEncoder(w)
+	for it.Next() {
+		if err := encoder.Encode(it.Object().Map()); err != nil {
+			return &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("write export data error:%v", err.Error())}
+		}
+		checkpoint.Cursor = it.Cursor()
+		checkpoint.Count++
+	}
+	return it.Err()
+}
+
+func exportCSV(it *QueryIterator, w io.Writer, checkpoint *ExportCheckpoint, fields []string) *APIError {
+	writer := csv.NewWriter(w)
+	pending := *checkpoint
+
+	// 写入成功后才更新 checkpoint
+	flush := func() *APIError {
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("write export data error:%v", err.Error())}
+		}
+		*checkpoint = pending
+		return nil
+	}
+
+	for it.Next() {
+		row := flattenMap("", it.Object().Map(), map[string]string{})
+
+		if len(pending.Columns) == 0 {
+			pending.Columns = csvColumns(row, fields)
+		}
+		if pending.Count == 0 && pending.Cursor.IsZero() {
+			writer.Write(pending.Columns)
+		}
+
+		record := make([]string, len(pending.Columns))
+		for i, column := range pending.Columns {
+			record[i] = row[column]
+		}
+		writer.Write(record)
+
+		pending.Cursor = it.Cursor()
+		pending.Count++
+		if pending.Count%exportPageSize == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+	return it.Err()
+}
+
+// 由展开后的数据得到列, fields 为空时使用所有列
+// 每个字段对应同名的列, 或以 `字段.` 开头的所有列
+func csvColumns(row map[string]string, fields []string) []string {
+	var all []string
+	for column := range row {
+		all = append(all, column)
+	}
+	sort.Strings(all)
+	if len(fields) == 0 {
+		return all
+	}
+
+	var columns []string
+	for _, field := range fields {
+		if _, ok := row[field]; ok {
+			columns = append(columns, field)
+			continue
+		}
+		var nested []string
+		for _, column := range all {
+			if strings.HasPrefix(column, field+".") {
+				nested = append(nested, column)
+			}
+		}
+		if len(nested) == 0 {
+			nested = []string{field}
+		}
+		columns = append(columns, nested...)
+	}
+	return columns
+}
+
+// 展开嵌套的map, 数组等其他类型编码为JSON
+func flattenMap(prefix string, data map[string]interface{}, result map[string]string) map[string]string {
+	for k, v := range data {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		switch value := v.(type) {
+		case map[string]interface{}:
+			flattenMap(key, value, result)
+		case string:
+			result[key] = value
+		case nil:
+			result[key] = ""
+		case bool:
+			result[key] = strconv.FormatBool(value)
+		case float64:
+			result[key] = strconv.FormatFloat(value, 'f', -1, 64)
+		default:
+			if b, err := json.Marshal(value); err == nil {
+				result[key] = string(b)
+			}
+		}
+	}
+	return result
+}
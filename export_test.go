@@ -0,0 +1,58 @@
+package skynology_test
+
+import (
+	"strings"
+	"testing"
+
+	sky "github.com/skynology/go-sdk"
+)
+
+// 第一次查询返回 results, 之后返回空列表
+type pagedHandler struct {
+	results []interface{}
+	calls   int
+}
+
+func (h *pagedHandler) SendRequest(params sky.HandlerRequestParams) (map[string]interface{}, *sky.APIError) {
+	h.calls++
+	if h.calls > 1 {
+		return map[string]interface{}{"results": []interface{}{}}, nil
+	}
+	return map[string]interface{}{"results": h.results}, nil
+}
+
+func TestExportCSVColumns(t *testing.T) {
+	row := map[string]interface{}{
+		"objectId":  "o1",
+		"createdAt": "2016-01-02T03:04:05Z",
+		"name":      "x",
+		"address":   map[string]interface{}{"city": "beijing", "geo": map[string]interface{}{"lat": 39.9}},
+		"tags":      []interface{}{"a", "b"},
+	}
+
+	tests := []struct {
+		name   string
+		fields []string
+		want   string
+	}{
+		{"all columns", nil, "address.city,address.geo.lat,createdAt,name,objectId,tags\nbeijing,39.9,2016-01-02T03:04:05Z,x,o1,\"[\"\"a\"\",\"\"b\"\"]\"\n"},
+		{"nested map selected", []string{"name", "address"}, "name,address.city,address.geo.lat\nx,beijing,39.9\n"},
+		{"nested path selected", []string{"address.geo", "tags"}, "address.geo.lat,tags\n39.9,\"[\"\"a\"\",\"\"b\"\"]\"\n"},
+		{"missing field", []string{"name", "phone"}, "name,phone\nx,\n"},
+	}
+
+	for _, tt := range tests {
+		app := sky.NewApp("app", "key")
+		app.SetRequestHandler(&pagedHandler{results: []interface{}{row}})
+
+		var out strings.Builder
+		checkpoint, err := app.Export(app.NewQuery("Post").Select(tt.fields...), &out, sky.ExportFormatCSV)
+		if err != nil {
+			t.Errorf("%s: export error %v", tt.name, err.String())
+			continue
+		}
+		if out.String() != tt.want || checkpoint.Count != 1 {
+			t.Errorf("%s: got %q, count %d, want %q", tt.name, out.String(), checkpoint.Count, tt.want)
+		}
+	}
+}
@@ -112,21 +112,32 @@ func (app *App) sendPutRequest(url string, data interface{}) (map[string]interfa
 }
 
 func (app *App) sendRequest(method string, url string, data interface{}) (map[string]interface{}, *APIError) {
-	sign, err := app.getRequestSign()
+	params, err := app.requestParams(method, url, data)
 	if err != nil {
 		return nil, &APIError{Code: -1, Error: fmt.Sprintf("marshal json data error:%v", err.Error())}
 	}
+
+	return app.handler.SendRequest(params)
+}
+
+func (app *App) requestParams(method string, url string, data interface{}) (HandlerRequestParams, error) {
 	params := HandlerRequestParams{}
+	params.Method = method
+	params.URL = url
+	params.Data = data
+
+	sign, err := app.getRequestSign()
+	if err != nil {
+		return params, err
+	}
+
 	params.AppId = app.ApplicationId
 	params.AppKey = app.ApplicationKey
 	params.MasterKey = app.MasterKey
 	params.RequestSign = sign
-	params.Method = method
-	params.URL = url
 	params.SessionToken = app.SessionToken
-	params.Data = data
 
-	return app.handler.SendRequest(params)
+	return params, nil
 }
 
 func (app *App) saveUserToDisk(user *User) error {
@@ -235,6 +246,8 @@ func GetInt64(v interface{}) int64 {
 
 func GetFloat64(v interface{}) float64 {
 	switch reply := v.(type) {
+	case float64:
+		return reply
 	case int:
 		return float64(reply)
 	case int64:
@@ -1 +1,487 @@
 package skynology
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// 地球半径(米), 用于距离与弧度的转换
+const EARTH_RADIUS_METERS = 6378100.0
+
+// 创建坐标, 与 GeoJSON 一致, 经度在前, 纬度在后
+func NewCoordinate(longitude, latitude float64) Coordinate {
+	return Coordinate{CoordType(longitude), CoordType(latitude)}
+}
+
+func (c Coordinate) Longitude() float64 {
+	return float64(c[0])
+}
+
+func (c Coordinate) Latitude() float64 {
+	return float64(c[1])
+}
+
+// GeoJSON 几何对象
+type Geometry interface {
+	GeometryType() string
+	Validate() error
+}
+
+// 点
+type Point struct {
+	Coordinates Coordinate
+}
+
+// 线
+type LineString struct {
+	Coordinates Coordinates
+}
+
+// 多边形, 第一个为外环(逆时针), 其余为内环(洞, 顺时针)
+type Polygon struct {
+	Coordinates MultiLine
+}
+
+type MultiPoint struct {
+	Coordinates Coordinates
+}
+
+type MultiLineString struct {
+	Coordinates MultiLine
+}
+
+type MultiPolygon struct {
+	Coordinates []MultiLine
+}
+
+type GeometryCollection struct {
+	Geometries []Geometry
+}
+
+// GeoJSON Feature, 带属性的几何对象
+type Feature struct {
+	ID         interface{}
+	Geometry   Geometry
+	Properties map[string]interface{}
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+type geoJSONCollection struct {
+	Type       string     `json:"type"`
+	Geometries []Geometry `json:"geometries"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	ID         interface{}            `json:"id,omitempty"`
+	Geometry   Geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type rawGeoJSON struct {
+	Type        string                 `json:"type"`
+	Coordinates json.RawMessage        `json:"coordinates"`
+	Geometries  []json.RawMessage      `json:"geometries"`
+	Geometry    json.RawMessage        `json:"geometry"`
+	ID          interface{}            `json:"id"`
+	Properties  map[string]interface{} `json:"properties"`
+}
+
+func (p Point) GeometryType() string              { return "Point" }
+func (l LineString) GeometryType() string         { return "LineString" }
+func (p Polygon) GeometryType() string            { return "Polygon" }
+func (m MultiPoint) GeometryType() string         { return "MultiPoint" }
+func (m MultiLineString) GeometryType() string    { return "MultiLineString" }
+func (m MultiPolygon) GeometryType() string       { return "MultiPolygon" }
+func (g GeometryCollection) GeometryType() string { return "GeometryCollection" }
+
+func (p Point) MarshalJSON() ([]byte, error) {
+	return json.Marshal(geoJSONGeometry{Type: p.GeometryType(), Coordinates: p.Coordinates})
+}
+
+func (l LineString) MarshalJSON() ([]byte, error) {
+	return json.Marshal(geoJSONGeometry{Type: l.GeometryType(), Coordinates: l.Coordinates})
+}
+
+func (p Polygon) MarshalJSON() ([]byte, error) {
+	return json.Marshal(geoJSONGeometry{Type: p.GeometryType(), Coordinates: p.Coordinates})
+}
+
+func (m MultiPoint) MarshalJSON() ([]byte, error) {
+	return json.Marshal(geoJSONGeometry{Type: m.GeometryType(), Coordinates: m.Coordinates})
+}
+
+func (m MultiLineString) MarshalJSON() ([]byte, error) {
+	return json.Marshal(geoJSONGeometry{Type: m.GeometryType(), Coordinates: m.Coordinates})
+}
+
+func (m MultiPolygon) MarshalJSON() ([]byte, error) {
+	return json.Marshal(geoJSONGeometry{Type: m.GeometryType(), Coordinates: m.Coordinates})
+}
+
+func (g GeometryCollection) MarshalJSON() ([]byte, error) {
+	geometries := g.Geometries
+	if geometries == nil {
+		geometries = []Geometry{}
+	}
+	return json.Marshal(geoJSONCollection{Type: g.GeometryType(), Geometries: geometries})
+}
+
+func (f Feature) MarshalJSON() ([]byte, error) {
+	return json.Marshal(geoJSONFeature{Type: "Feature", ID: f.ID, Geometry: f.Geometry, Properties: f.Properties})
+}
+
+func (p *Point) UnmarshalJSON(data []byte) error {
+	return unmarshalGeometryInto(data, p)
+}
+
+func (l *LineString) UnmarshalJSON(data []byte) error {
+	return unmarshalGeometryInto(data, l)
+}
+
+func (p *Polygon) UnmarshalJSON(data []byte) error {
+	return unmarshalGeometryInto(data, p)
+}
+
+func (m *MultiPoint) UnmarshalJSON(data []byte) error {
+	return unmarshalGeometryInto(data, m)
+}
+
+func (m *MultiLineString) UnmarshalJSON(data []byte) error {
+	return unmarshalGeometryInto(data, m)
+}
+
+func (m *MultiPolygon) UnmarshalJSON(data []byte) error {
+	return unmarshalGeometryInto(data, m)
+}
+
+func (g *GeometryCollection) UnmarshalJSON(data []byte) error {
+	return unmarshalGeometryInto(data, g)
+}
+
+func (f *Feature) UnmarshalJSON(data []byte) error {
+	var raw rawGeoJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Type != "Feature" {
+		return fmt.Errorf("geojson: expected Feature, got '%s'", raw.Type)
+	}
+
+	f.ID = raw.ID
+	f.Properties = raw.Properties
+	f.Geometry = nil
+	if len(raw.Geometry) > 0 && string(raw.Geometry) != "null" {
+		geometry, err := ParseGeometry(raw.Geometry)
+		if err != nil {
+			return err
+		}
+		f.Geometry = geometry
+	}
+	return nil
+}
+
+// 解析 GeoJSON 几何对象
+func ParseGeometry(data []byte) (Geometry, error) {
+	var raw rawGeoJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	switch raw.Type {
+	case "Point":
+		var g Point
+		if err := json.Unmarshal(raw.Coordinates, &g.Coordinates); err != nil {
+			return nil, err
+		}
+		return g, nil
+	case "LineString":
+		var g LineString
+		if err := json.Unmarshal(raw.Coordinates, &g.Coordinates); err != nil {
+			return nil, err
+		}
+		return g, nil
+	case "Polygon":
+		var g Polygon
+		if err := json.Unmarshal(raw.Coordinates, &g.Coordinates); err != nil {
+			return nil, err
+		}
+		return g, nil
+	case "MultiPoint":
+		var g MultiPoint
+		if err := json.Unmarshal(raw.Coordinates, &g.Coordinates); err != nil {
+			return nil, err
+		}
+		return g, nil
+	case "MultiLineString":
+		var g MultiLineString
+		if err := json.Unmarshal(raw.Coordinates, &g.Coordinates); err != nil {
+			return nil, err
+		}
+		return g, nil
+	case "MultiPolygon":
+		var g MultiPolygon
+		if err := json.Unmarshal(raw.Coordinates, &g.Coordinates); err != nil {
+			return nil, err
+		}
+		return g, nil
+	case "GeometryCollection":
+		var g GeometryCollection
+		for _, item := range raw.Geometries {
+			geometry, err := ParseGeometry(item)
+			if err != nil {
+				return nil, err
+			}
+			g.Geometries = append(g.Geometries, geometry)
+		}
+		return g, nil
+	}
+
+	return nil, fmt.Errorf("geojson: unknown geometry type '%s'", raw.Type)
+}
+
+// 解析到指定类型, 类型不一致时返回错误
+func unmarshalGeometryInto(data []byte, target Geometry) error {
+	geometry, err := ParseGeometry(data)
+	if err != nil {
+		return err
+	}
+	if geometry.GeometryType() != target.GeometryType() {
+		return fmt.Errorf("geojson: expected %s, got '%s'", target.GeometryType(), geometry.GeometryType())
+	}
+
+	switch t := target.(type) {
+	case *Point:
+		*t = geometry.(Point)
+	case *LineString:
+		*t = geometry.(LineString)
+	case *Polygon:
+		*t = geometry.(Polygon)
+	case *MultiPoint:
+		*t = geometry.(MultiPoint)
+	case *MultiLineString:
+		*t = geometry.(MultiLineString)
+	case *MultiPolygon:
+		*t = geometry.(MultiPolygon)
+	case *GeometryCollection:
+		*t = geometry.(GeometryCollection)
+	}
+	return nil
+}
+
+// 检查经纬度范围
+func (c Coordinate) Validate() error {
+	if c.Longitude() < -180 || c.Longitude() > 180 {
+		return fmt.Errorf("geojson: longitude %v out of range [-180, 180]", c.Longitude())
+	}
+	if c.Latitude() < -90 || c.Latitude() > 90 {
+		return fmt.Errorf("geojson: latitude %v out of range [-90, 90]", c.Latitude())
+	}
+	return nil
+}
+
+func (c Coordinates) validate() error {
+	for _, coord := range c {
+		if err := coord.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 检查环: 至少4个点, 首尾相同, 且方向正确
+// 外环需为逆时针, 内环需为顺时针
+func (c Coordinates) validateRing(exterior bool) error {
+	if len(c) < 4 {
+		return errors.New("geojson: linear ring must have at least 4 positions")
+	}
+	if c[0] != c[len(c)-1] {
+		return errors.New("geojson: linear ring is not closed")
+	}
+	if err := c.validate(); err != nil {
+		return err
+	}
+
+	area := c.signedArea()
+	if exterior && area < 0 {
+		return errors.New("geojson: exterior ring must be counterclockwise")
+	}
+	if !exterior && area > 0 {
+		return errors.New("geojson: interior ring must be clockwise")
+	}
+	return nil
+}
+
+// 按经纬度平面计算有向面积, 逆时针为正
+func (c Coordinates) signedArea() float64 {
+	var area float64
+	for i := 0; i+1 < len(c); i++ {
+		area += c[i].Longitude()*c[i+1].Latitude() - c[i+1].Longitude()*c[i].Latitude()
+	}
+	return area / 2
+}
+
+func (p Point) Validate() error {
+	return p.Coordinates.Validate()
+}
+
+func (l LineString) Validate() error {
+	if len(l.Coordinates) < 2 {
+		return errors.New("geojson: LineString must have at least 2 positions")
+	}
+	return l.Coordinates.validate()
+}
+
+func (p Polygon) Validate() error {
+	if len(p.Coordinates) == 0 {
+		return errors.New("geojson: Polygon must have an exterior ring")
+	}
+	for i, ring := range p.Coordinates {
+		if err := ring.validateRing(i == 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m MultiPoint) Validate() error {
+	return m.Coordinates.validate()
+}
+
+func (m MultiLineString) Validate() error {
+	for _, line := range m.Coordinates {
+		if err := (LineString{Coordinates: line}).Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m MultiPolygon) Validate() error {
+	for _, polygon := range m.Coordinates {
+		if err := (Polygon{Coordinates: polygon}).Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g GeometryCollection) Validate() error {
+	for _, geometry := range g.Geometries {
+		if geometry == nil {
+			return errors.New("geojson: GeometryCollection contains nil geometry")
+		}
+		if err := geometry.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f Feature) Validate() error {
+	if f.Geometry == nil {
+		return nil
+	}
+	return f.Geometry.Validate()
+}
+
+// 设置 GeoJSON 字段, 保存前可先调用 Validate 检查
+func (obj *Object) SetGeometry(field string, geometry Geometry) *Object {
+	return obj.Set(field, geometry)
+}
+
+// 获取 GeoJSON 字段
+func (obj *Object) GetGeometry(field string) (Geometry, error) {
+	v := obj.Get(field)
+	if v == nil {
+		return nil, fmt.Errorf("geojson: field '%s' is empty", field)
+	}
+	if g, ok := v.(Geometry); ok {
+		return g, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGeometry(b)
+}
+
+// 设置地理位置字段
+func (obj *Object) SetGeoPoint(field string, point Coordinate) *Object {
+	return obj.Set(field, Point{Coordinates: point})
+}
+
+// 获取地理位置字段, 字段不是 GeoJSON Point 时返回 false
+func (obj *Object) GetGeoPoint(field string) (Coordinate, bool) {
+	var result Coordinate
+
+	m := obj.GetMap(field)
+	if m == nil || m["type"] != "Point" {
+		return result, false
+	}
+	coords, ok := m["coordinates"].([]interface{})
+	if !ok || len(coords) != 2 {
+		return result, false
+	}
+	lng, ok1 := coords[0].(float64)
+	lat, ok2 := coords[1].(float64)
+	if !ok1 || !ok2 {
+		return result, false
+	}
+
+	return NewCoordinate(lng, lat), true
+}
+
+// 查找指定点附近的数据, 结果按距离由近到远排序, 并忽略 OrderBy 的设置
+// maxDistance 单位为米, 小于等于0时不限制距离
+func (query *Query) Near(field string, point Coordinate, maxDistance float64) *Query {
+	near := map[string]interface{}{"$geometry": Point{Coordinates: point}}
+	if maxDistance > 0 {
+		near["$maxDistance"] = maxDistance
+	}
+	return query.addCondition(field, "$near", near)
+}
+
+// 查找在矩形范围内的数据
+func (query *Query) WithinBox(field string, southwest, northeast Coordinate) *Query {
+	return query.addCondition(field, "$geoWithin", map[string]interface{}{"$box": Coordinates{southwest, northeast}})
+}
+
+// 查找在多边形范围内的数据, 多边形未闭合时会自动闭合
+func (query *Query) WithinPolygon(field string, points Coordinates) *Query {
+	ring := append(Coordinates(nil), points...)
+	if len(ring) > 0 && ring[0] != ring[len(ring)-1] {
+		ring = append(ring, ring[0])
+	}
+	polygon := Polygon{Coordinates: MultiLine{ring}}
+	return query.addCondition(field, "$geoWithin", map[string]interface{}{"$geometry": polygon})
+}
+
+// 查找在球面圆形范围内的数据, radius 单位为米
+func (query *Query) WithinCenterSphere(field string, center Coordinate, radius float64) *Query {
+	sphere := []interface{}{center, radius / EARTH_RADIUS_METERS}
+	return query.addCondition(field, "$geoWithin", map[string]interface{}{"$centerSphere": sphere})
+}
+
+// 查找与指定几何对象相交的数据
+func (query *Query) GeoIntersects(field string, geometry Geometry) *Query {
+	return query.addCondition(field, "$geoIntersects", map[string]interface{}{"$geometry": geometry})
+}
+
+// 是否有 $near 条件, 有则由服务器按距离排序
+func (query *Query) hasNear() bool {
+	for _, v := range query.where {
+		if cond, ok := v.(map[string]interface{}); ok {
+			if _, ok := cond["$near"]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package skynology_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	sky "github.com/skynology/go-sdk"
+)
+
+func ring(points ...[2]float64) sky.Coordinates {
+	var c sky.Coordinates
+	for _, p := range points {
+		c = append(c, sky.NewCoordinate(p[0], p[1]))
+	}
+	return c
+}
+
+func TestPolygonValidate(t *testing.T) {
+	square := ring([2]float64{0, 0}, [2]float64{10, 0}, [2]float64{10, 10}, [2]float64{0, 10}, [2]float64{0, 0})
+	clockwise := ring([2]float64{0, 0}, [2]float64{0, 10}, [2]float64{10, 10}, [2]float64{10, 0}, [2]float64{0, 0})
+	hole := ring([2]float64{2, 2}, [2]float64{2, 4}, [2]float64{4, 4}, [2]float64{4, 2}, [2]float64{2, 2})
+	counterclockwiseHole := ring([2]float64{2, 2}, [2]float64{4, 2}, [2]float64{4, 4}, [2]float64{2, 4}, [2]float64{2, 2})
+
+	tests := []struct {
+		name    string
+		polygon sky.Polygon
+		err     string
+	}{
+		{"valid", sky.Polygon{Coordinates: sky.MultiLine{square}}, ""},
+		{"valid with hole", sky.Polygon{Coordinates: sky.MultiLine{square, hole}}, ""},
+		{"no rings", sky.Polygon{}, "must have an exterior ring"},
+		{"too few positions", sky.Polygon{Coordinates: sky.MultiLine{square[:3]}}, "at least 4 positions"},
+		{"not closed", sky.Polygon{Coordinates: sky.MultiLine{square[:4]}}, "not closed"},
+		{"clockwise exterior", sky.Polygon{Coordinates: sky.MultiLine{clockwise}}, "exterior ring must be counterclockwise"},
+		{"counterclockwise hole", sky.Polygon{Coordinates: sky.MultiLine{square, counterclockwiseHole}}, "interior ring must be clockwise"},
+		{"latitude out of range", sky.Polygon{Coordinates: sky.MultiLine{ring([2]float64{0, 0}, [2]float64{10, 0}, [2]float64{10, 91}, [2]float64{0, 0})}}, "latitude"},
+	}
+
+	for _, tt := range tests {
+		err := tt.polygon.Validate()
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestGeometryJSONRoundTrip(t *testing.T) {
+	square := ring([2]float64{0, 0}, [2]float64{10, 0}, [2]float64{10, 10}, [2]float64{0, 10}, [2]float64{0, 0})
+	line := ring([2]float64{116.4, 39.9}, [2]float64{121.47, 31.23})
+
+	tests := []struct {
+		name     string
+		geometry sky.Geometry
+		json     string
+	}{
+		{"Point", sky.Point{Coordinates: sky.NewCoordinate(116.4, 39.9)}, `{"type":"Point","coordinates":[116.4,39.9]}`},
+		{"LineString", sky.LineString{Coordinates: line}, `{"type":"LineString","coordinates":[[116.4,39.9],[121.47,31.23]]}`},
+		{"Polygon", sky.Polygon{Coordinates: sky.MultiLine{square}}, `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]]]}`},
+		{"MultiPoint", sky.MultiPoint{Coordinates: line}, `{"type":"MultiPoint","coordinates":[[116.4,39.9],[121.47,31.23]]}`},
+		{"MultiLineString", sky.MultiLineString{Coordinates: sky.MultiLine{line}}, `{"type":"MultiLineString","coordinates":[[[116.4,39.9],[121.47,31.23]]]}`},
+		{"MultiPolygon", sky.MultiPolygon{Coordinates: []sky.MultiLine{{square}}}, `{"type":"MultiPolygon","coordinates":[[[[0,0],[10,0],[10,10],[0,10],[0,0]]]]}`},
+		{"GeometryCollection", sky.GeometryCollection{Geometries: []sky.Geometry{sky.Point{Coordinates: sky.NewCoordinate(1, 2)}, sky.LineString{Coordinates: line}}},
+			`{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[1,2]},{"type":"LineString","coordinates":[[116.4,39.9],[121.47,31.23]]}]}`},
+	}
+
+	for _, tt := range tests {
+		b, err := json.Marshal(tt.geometry)
+		if err != nil {
+			t.Errorf("%s: marshal error %v", tt.name, err)
+			continue
+		}
+		if string(b) != tt.json {
+			t.Errorf("%s: marshal got %s, want %s", tt.name, b, tt.json)
+		}
+
+		parsed, err := sky.ParseGeometry(b)
+		if err != nil {
+			t.Errorf("%s: parse error %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(parsed, tt.geometry) {
+			t.Errorf("%s: round trip got %#v, want %#v", tt.name, parsed, tt.geometry)
+		}
+	}
+}
+
+func TestFeatureJSONRoundTrip(t *testing.T) {
+	feature := sky.Feature{
+		ID:         "a",
+		Geometry:   sky.Point{Coordinates: sky.NewCoordinate(1, 2)},
+		Properties: map[string]interface{}{"name": "home"},
+	}
+	b, err := json.Marshal(feature)
+	if err != nil {
+		t.Fatalf("marshal error %v", err)
+	}
+
+	var parsed sky.Feature
+	if err := json.Unmarshal(b, &parsed); err != nil {
+		t.Fatalf("unmarshal error %v", err)
+	}
+	if !reflect.DeepEqual(parsed, feature) {
+		t.Errorf("round trip got %#v, want %#v", parsed, feature)
+	}
+}
+
+func TestGeometryTypeMismatch(t *testing.T) {
+	var polygon sky.Polygon
+	if err := json.Unmarshal([]byte(`{"type":"Point","coordinates":[1,2]}`), &polygon); err == nil {
+		t.Error("expected error unmarshaling Point into Polygon")
+	}
+	if _, err := sky.ParseGeometry([]byte(`{"type":"Circle"}`)); err == nil {
+		t.Error("expected error for unknown geometry type")
+	}
+}
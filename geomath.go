@@ -0,0 +1,217 @@
+package skynology
+
+import "math"
+
+// 矩形范围
+type BoundingBox struct {
+	Southwest Coordinate
+	Northeast Coordinate
+}
+
+// 与另一点之间的球面距离(米), 与服务器 Near/WithinCenterSphere 使用相同的地球半径
+func (c Coordinate) DistanceTo(other Coordinate) float64 {
+	lat1 := toRadians(c.Latitude())
+	lat2 := toRadians(other.Latitude())
+	dLat := lat2 - lat1
+	dLng := toRadians(other.Longitude() - c.Longitude())
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * EARTH_RADIUS_METERS * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// 到另一点的初始方位角(度), 正北为0, 顺时针 [0, 360)
+func (c Coordinate) BearingTo(other Coordinate) float64 {
+	lat1 := toRadians(c.Latitude())
+	lat2 := toRadians(other.Latitude())
+	dLng := toRadians(other.Longitude() - c.Longitude())
+
+	y := math.Sin(dLng) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLng)
+	return math.Mod(toDegrees(math.Atan2(y, x))+360, 360)
+}
+
+// 是否在以 center 为圆心, radius(米) 为半径的球面圆内, 与 WithinCenterSphere 一致
+func (c Coordinate) WithinCenterSphere(center Coordinate, radius float64) bool {
+	return c.DistanceTo(center) <= radius
+}
+
+// 是否在矩形范围内(包括边界), 与 WithinBox 一致
+func (b BoundingBox) Contains(c Coordinate) bool {
+	return c.Longitude() >= b.Southwest.Longitude() && c.Longitude() <= b.Northeast.Longitude() &&
+		c.Latitude() >= b.Southwest.Latitude() && c.Latitude() <= b.Northeast.Latitude()
+}
+
+func (b BoundingBox) extend(c Coordinate) BoundingBox {
+	b.Southwest = NewCoordinate(math.Min(b.Southwest.Longitude(), c.Longitude()), math.Min(b.Southwest.Latitude(), c.Latitude()))
+	b.Northeast = NewCoordinate(math.Max(b.Northeast.Longitude(), c.Longitude()), math.Max(b.Northeast.Latitude(), c.Latitude()))
+	return b
+}
+
+// 几何对象的矩形范围, 没有任何坐标时返回 false
+func GeometryBounds(geometry Geometry) (BoundingBox, bool) {
+	var box BoundingBox
+	found := false
+	for _, c := range geometryCoordinates(geometry) {
+		if !found {
+			box = BoundingBox{Southwest: c, Northeast: c}
+			found = true
+			continue
+		}
+		box = box.extend(c)
+	}
+	return box, found
+}
+
+func geometryCoordinates(geometry Geometry) Coordinates {
+	var result Coordinates
+	switch g := geometry.(type) {
+	case Point:
+		result = append(result, g.Coordinates)
+	case LineString:
+		result = append(result, g.Coordinates...)
+	case MultiPoint:
+		result = append(result, g.Coordinates...)
+	case Polygon:
+		for _, ring := range g.Coordinates {
+			result = append(result, ring...)
+		}
+	case MultiLineString:
+		for _, line := range g.Coordinates {
+			result = append(result, line...)
+		}
+	case MultiPolygon:
+		for _, polygon := range g.Coordinates {
+			for _, ring := range polygon {
+				result = append(result, ring...)
+			}
+		}
+	case GeometryCollection:
+		for _, item := range g.Geometries {
+			result = append(result, geometryCoordinates(item)...)
+		}
+	}
+	return result
+}
+
+// 点是否在环内(包括边界)
+// 按经纬度平面计算, 边为直线; 服务器的 WithinPolygon/GeoIntersects 按球面大圆计算边,
+// 边较长或靠近极点时结果可能与服务器不同, 也不处理跨越180度经线的环
+func (c Coordinates) ContainsPoint(point Coordinate) bool {
+	inside := false
+	x, y := point.Longitude(), point.Latitude()
+	for i, j := 0, len(c)-1; i < len(c); j, i = i, i+1 {
+		xi, yi := c[i].Longitude(), c[i].Latitude()
+		xj, yj := c[j].Longitude(), c[j].Latitude()
+
+		if onSegment(c[j], c[i], point) {
+			return true
+		}
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// 点是否在多边形内, 在内环(洞)中的点不算在内
+// 与 ContainsPoint 相同按平面计算, 仅适合边较短的多边形做近似判断
+func (p Polygon) Contains(point Coordinate) bool {
+	if len(p.Coordinates) == 0 || !p.Coordinates[0].ContainsPoint(point) {
+		return false
+	}
+	for _, hole := range p.Coordinates[1:] {
+		if hole.ContainsPoint(point) && !onRing(hole, point) {
+			return false
+		}
+	}
+	return true
+}
+
+// 点是否在任一多边形内, 按平面计算, 限制同 Polygon.Contains
+func (m MultiPolygon) Contains(point Coordinate) bool {
+	for _, polygon := range m.Coordinates {
+		if (Polygon{Coordinates: polygon}).Contains(point) {
+			return true
+		}
+	}
+	return false
+}
+
+// 使用 Douglas-Peucker 算法简化线, tolerance 单位为米
+func (c Coordinates) Simplify(tolerance float64) Coordinates {
+	if len(c) < 3 || tolerance <= 0 {
+		return append(Coordinates(nil), c...)
+	}
+
+	keep := make([]bool, len(c))
+	keep[0], keep[len(c)-1] = true, true
+	simplifyRange(c, 0, len(c)-1, tolerance, keep)
+
+	var result Coordinates
+	for i, k := range keep {
+		if k {
+			result = append(result, c[i])
+		}
+	}
+	return result
+}
+
+func simplifyRange(c Coordinates, first, last int, tolerance float64, keep []bool) {
+	maxDistance := 0.0
+	index := -1
+	for i := first + 1; i < last; i++ {
+		if d := crossTrackDistance(c[i], c[first], c[last]); d > maxDistance {
+			maxDistance = d
+			index = i
+		}
+	}
+	if index >= 0 && maxDistance > tolerance {
+		keep[index] = true
+		simplifyRange(c, first, index, tolerance, keep)
+		simplifyRange(c, index, last, tolerance, keep)
+	}
+}
+
+// 点到线段的距离(米), 在局部等距投影下计算, 适用于较短的线段
+func crossTrackDistance(point, start, end Coordinate) float64 {
+	scale := math.Cos(toRadians(point.Latitude()))
+	project := func(c Coordinate) (float64, float64) {
+		return toRadians(c.Longitude()) * scale * EARTH_RADIUS_METERS, toRadians(c.Latitude()) * EARTH_RADIUS_METERS
+	}
+	px, py := project(point)
+	ax, ay := project(start)
+	bx, by := project(end)
+
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if dx != 0 || dy != 0 {
+		t = math.Max(0, math.Min(1, ((px-ax)*dx+(py-ay)*dy)/(dx*dx+dy*dy)))
+	}
+	return math.Hypot(px-(ax+t*dx), py-(ay+t*dy))
+}
+
+func onRing(ring Coordinates, point Coordinate) bool {
+	for i := 0; i+1 < len(ring); i++ {
+		if onSegment(ring[i], ring[i+1], point) {
+			return true
+		}
+	}
+	return false
+}
+
+func onSegment(a, b, p Coordinate) bool {
+	cross := (b.Longitude()-a.Longitude())*(p.Latitude()-a.Latitude()) - (b.Latitude()-a.Latitude())*(p.Longitude()-a.Longitude())
+	if math.Abs(cross) > 1e-12 {
+		return false
+	}
+	return p.Longitude() >= math.Min(a.Longitude(), b.Longitude()) && p.Longitude() <= math.Max(a.Longitude(), b.Longitude()) &&
+		p.Latitude() >= math.Min(a.Latitude(), b.Latitude()) && p.Latitude() <= math.Max(a.Latitude(), b.Latitude())
+}
+
+func toRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func toDegrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
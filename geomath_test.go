@@ -0,0 +1,138 @@
+package skynology_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	sky "github.com/skynology/go-sdk"
+)
+
+func TestDistanceAndBearing(t *testing.T) {
+	degree := sky.EARTH_RADIUS_METERS * math.Pi / 180
+	origin := sky.NewCoordinate(0, 0)
+
+	tests := []struct {
+		name     string
+		from, to sky.Coordinate
+		distance float64
+		bearing  float64
+	}{
+		{"north", origin, sky.NewCoordinate(0, 1), degree, 0},
+		{"east", origin, sky.NewCoordinate(1, 0), degree, 90},
+		{"south", origin, sky.NewCoordinate(0, -1), degree, 180},
+		{"west", origin, sky.NewCoordinate(-1, 0), degree, 270},
+		{"pole", origin, sky.NewCoordinate(0, 90), 90 * degree, 0},
+		{"beijing to shanghai", sky.NewCoordinate(116.4, 39.9), sky.NewCoordinate(121.47, 31.23), 1068300, 153.05},
+	}
+
+	for _, tt := range tests {
+		if d := tt.from.DistanceTo(tt.to); math.Abs(d-tt.distance) > tt.distance*1e-3 {
+			t.Errorf("%s: distance got %v, want %v", tt.name, d, tt.distance)
+		}
+		if b := tt.from.BearingTo(tt.to); math.Abs(b-tt.bearing) > 0.01 {
+			t.Errorf("%s: bearing got %v, want %v", tt.name, b, tt.bearing)
+		}
+	}
+
+	if d := origin.DistanceTo(origin); d != 0 {
+		t.Errorf("distance to itself got %v", d)
+	}
+	if !sky.NewCoordinate(0, 0.5).WithinCenterSphere(origin, degree) || sky.NewCoordinate(0, 1.5).WithinCenterSphere(origin, degree) {
+		t.Error("WithinCenterSphere does not match DistanceTo")
+	}
+}
+
+func TestPolygonContains(t *testing.T) {
+	square := ring([2]float64{0, 0}, [2]float64{10, 0}, [2]float64{10, 10}, [2]float64{0, 10}, [2]float64{0, 0})
+	hole := ring([2]float64{2, 2}, [2]float64{2, 4}, [2]float64{4, 4}, [2]float64{4, 2}, [2]float64{2, 2})
+	polygon := sky.Polygon{Coordinates: sky.MultiLine{square, hole}}
+
+	tests := []struct {
+		name  string
+		point sky.Coordinate
+		want  bool
+	}{
+		{"inside", sky.NewCoordinate(1, 1), true},
+		{"inside hole", sky.NewCoordinate(3, 3), false},
+		{"hole boundary", sky.NewCoordinate(2, 3), true},
+		{"exterior boundary", sky.NewCoordinate(0, 5), true},
+		{"vertex", sky.NewCoordinate(10, 10), true},
+		{"outside", sky.NewCoordinate(11, 1), false},
+		{"outside below", sky.NewCoordinate(5, -1), false},
+	}
+
+	for _, tt := range tests {
+		if got := polygon.Contains(tt.point); got != tt.want {
+			t.Errorf("%s: Contains(%v) got %v, want %v", tt.name, tt.point, got, tt.want)
+		}
+	}
+
+	other := ring([2]float64{20, 20}, [2]float64{30, 20}, [2]float64{30, 30}, [2]float64{20, 20})
+	multi := sky.MultiPolygon{Coordinates: []sky.MultiLine{{square, hole}, {other}}}
+	if !multi.Contains(sky.NewCoordinate(25, 21)) || multi.Contains(sky.NewCoordinate(3, 3)) || multi.Contains(sky.NewCoordinate(15, 15)) {
+		t.Error("MultiPolygon.Contains does not match its polygons")
+	}
+}
+
+func TestGeometryBounds(t *testing.T) {
+	line := sky.LineString{Coordinates: ring([2]float64{1, 5}, [2]float64{-3, 2}, [2]float64{4, -1})}
+	box, ok := sky.GeometryBounds(line)
+	want := sky.BoundingBox{Southwest: sky.NewCoordinate(-3, -1), Northeast: sky.NewCoordinate(4, 5)}
+	if !ok || box != want {
+		t.Fatalf("bounds got %v %v, want %v", box, ok, want)
+	}
+	if !box.Contains(sky.NewCoordinate(4, 5)) || box.Contains(sky.NewCoordinate(4.1, 0)) {
+		t.Error("BoundingBox.Contains does not include the border only")
+	}
+	if _, ok := sky.GeometryBounds(sky.GeometryCollection{}); ok {
+		t.Error("empty geometry should have no bounds")
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      sky.Coordinates
+		tolerance float64
+		want      sky.Coordinates
+	}{
+		{
+			// 中间点偏离约1.1米
+			"drop close point",
+			ring([2]float64{0, 0}, [2]float64{0.0001, 0.00001}, [2]float64{0.001, 0}),
+			5,
+			ring([2]float64{0, 0}, [2]float64{0.001, 0}),
+		},
+		{
+			"keep far point",
+			ring([2]float64{0, 0}, [2]float64{0.0005, 0.001}, [2]float64{0.001, 0}),
+			5,
+			ring([2]float64{0, 0}, [2]float64{0.0005, 0.001}, [2]float64{0.001, 0}),
+		},
+		{
+			"keep only far points",
+			ring([2]float64{0, 0}, [2]float64{0.00025, 0.00051}, [2]float64{0.0005, 0.001}, [2]float64{0.00075, 0.00051}, [2]float64{0.001, 0}),
+			5,
+			ring([2]float64{0, 0}, [2]float64{0.0005, 0.001}, [2]float64{0.001, 0}),
+		},
+		{
+			"zero tolerance",
+			ring([2]float64{0, 0}, [2]float64{0.0001, 0.00001}, [2]float64{0.001, 0}),
+			0,
+			ring([2]float64{0, 0}, [2]float64{0.0001, 0.00001}, [2]float64{0.001, 0}),
+		},
+		{
+			"two points",
+			ring([2]float64{0, 0}, [2]float64{1, 1}),
+			100,
+			ring([2]float64{0, 0}, [2]float64{1, 1}),
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.line.Simplify(tt.tolerance); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
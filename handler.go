@@ -52,24 +52,38 @@ func (d *DefaultHandler) getHttpRequest(params HandlerRequestParams) (*http.Requ
 		return request, err
 	}
 
-	request.Header.Add("Content-Type", "application/json")
-	request.Header.Add("User-Agent", fmt.Sprintf("Skynology-Golang/%v (%v;%v;)", SDK_VERSION, runtime.GOOS, runtime.GOARCH))
+	for k, v := range requestHeaders(params) {
+		request.Header.Add(k, v)
+	}
+	request.ContentLength = length
+
+	return request, nil
+}
 
-	request.Header.Add(X_CLIENT_VERSION_HEADER, fmt.Sprintf("go-%v", SDK_VERSION))
-	request.Header.Add(X_APPLICATION_ID_HEADER, params.AppId)
-	request.Header.Add(X_REQUEST_SIGN_HEADER, params.RequestSign)
+// 请求的http头
+func requestHeaders(params HandlerRequestParams) map[string]string {
+	headers := map[string]string{}
+	for k, v := range params.Headers {
+		headers[k] = v
+	}
+
+	headers["Content-Type"] = "application/json"
+	headers["User-Agent"] = fmt.Sprintf("Skynology-Golang/%v (%v;%v;)", SDK_VERSION, runtime.GOOS, runtime.GOARCH)
+
+	headers[X_CLIENT_VERSION_HEADER] = fmt.Sprintf("go-%v", SDK_VERSION)
+	headers[X_APPLICATION_ID_HEADER] = params.AppId
+	headers[X_REQUEST_SIGN_HEADER] = params.RequestSign
 	if params.SessionToken != "" {
-		request.Header.Add(X_SESSION_TOKEN_HEADER, params.SessionToken)
+		headers[X_SESSION_TOKEN_HEADER] = params.SessionToken
 	}
 	if params.WeixinId != "" {
-		request.Header.Add(X_WEIXIN_ID_HEADER, params.WeixinId)
+		headers[X_WEIXIN_ID_HEADER] = params.WeixinId
 	}
 	if params.WeixinType != "" {
-		request.Header.Add(X_WEIXIN_TYPE_HEADER, params.WeixinType)
+		headers[X_WEIXIN_TYPE_HEADER] = params.WeixinType
 	}
-	request.ContentLength = length
 
-	return request, nil
+	return headers
 }
 
 func (d DefaultHandler) SendRequest(params HandlerRequestParams) (map[string]interface{}, *APIError) {
@@ -107,8 +121,9 @@ func (d DefaultHandler) SendRequest(params HandlerRequestParams) (map[string]int
 	} else {
 		err = json.Unmarshal(buf.Bytes(), &apiError)
 		if err != nil {
-			return m, &APIError{Code: -1, Error: fmt.Sprintf("parse response data to json(failed). %v", err.Error())}
+			return m, &APIError{Code: ERROR_CODE_CLIENT, StatusCode: response.StatusCode, Error: fmt.Sprintf("parse response data to json(failed). %v", err.Error())}
 		}
+		apiError.StatusCode = response.StatusCode
 		return m, &apiError
 	}
 
@@ -0,0 +1,435 @@
+package skynology
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 导入格式
+type ImportFormat string
+
+const (
+	ImportFormatJSONL ImportFormat = "jsonl"
+	ImportFormatCSV   ImportFormat = "csv"
+)
+
+// 导入时列的类型, 字符串值会按类型转换
+type ColumnType int
+
+const (
+	ColumnTypeString ColumnType = iota
+	ColumnTypeNumber
+	ColumnTypeBool
+	// RFC3339 格式, 转换为服务器的 Date 类型
+	ColumnTypeDate
+	// "经度,纬度" 格式, 转换为 GeoJSON Point
+	ColumnTypeGeoPoint
+	// JSON 字符串
+	ColumnTypeJSON
+)
+
+// 导入选项
+type ImportOptions struct {
+	// 每批数量, 默认50
+	BatchSize int
+	// 同时发送的批次数, 默认1
+	// 设置了 UpsertKey 时按其值分配批次, 相同值的行由同一个协程依次写入
+	Concurrency int
+	// 按此字段匹配已有数据, 匹配到时更新, 否则新建
+	// 数字按数值匹配, 如 1 与 1.0 视为相同
+	UpsertKey string
+	// 各列的类型, 未设置的列不转换
+	Columns map[string]ColumnType
+	// 写入失败的行, 每行一个JSON: {"line":1,"row":{...},"error":{...}}
+	RejectWriter io.Writer
+}
+
+// 导入结果
+type ImportReport struct {
+	Created  int
+	Updated  int
+	Rejected int
+}
+
+type importRow struct {
+	line int
+	raw  map[string]interface{}
+	data map[string]interface{}
+}
+
+type importer struct {
+	app     *App
+	name    string
+	options ImportOptions
+	report  ImportReport
+	mu      sync.Mutex
+}
+
+// 从 JSON Lines 或 CSV 导入数据到指定资源
+// CSV 第一行为列名, `a.b` 形式的列会还原为嵌套的map, 空值的列会被忽略
+func (app *App) Import(resourceName string, r io.Reader, format ImportFormat, options *ImportOptions) (*ImportReport, *APIError) {
+	imp := &importer{app: app, name: resourceName}
+	if options != nil {
+		imp.options = *options
+	}
+	if imp.options.BatchSize <= 0 {
+		imp.options.BatchSize = 50
+	}
+	if imp.options.Concurrency <= 0 {
+		imp.options.Concurrency = 1
+	}
+
+	// 每个协程有自己的批次, 避免不同协程同时查询并新建 UpsertKey 相同的数据
+	workers := make([]chan []importRow, imp.options.Concurrency)
+	pending := make([][]importRow, imp.options.Concurrency)
+	var wg sync.WaitGroup
+	for i := range workers {
+		workers[i] = make(chan []importRow)
+		wg.Add(1)
+		go func(batches chan []importRow) {
+			defer wg.Done()
+			for batch := range batches {
+				imp.send(batch)
+			}
+		}(workers[i])
+	}
+
+	next := 0
+	emit := func(row importRow) {
+		var err error
+		row.data, err = imp.coerce(row.raw)
+		if err != nil {
+			imp.reject(row, &APIError{Code: ERROR_CODE_CLIENT, Error: err.Error()})
+			return
+		}
+
+		i := next
+		next = (next + 1) % len(workers)
+		if v, ok := row.data[imp.options.UpsertKey]; ok && imp.options.UpsertKey != "" {
+			h := fnv.New32a()
+			h.Write([]byte(upsertKey(v)))
+			i = int(h.Sum32() % uint32(len(workers)))
+		}
+
+		pending[i] = append(pending[i], row)
+		if len(pending[i]) >= imp.options.BatchSize {
+			workers[i] <- pending[i]
+			pending[i] = nil
+		}
+	}
+
+	var err *APIError
+	switch format {
+	case ImportFormatJSONL:
+		err = readJSONL(r, emit, imp.reject)
+	case ImportFormatCSV:
+		err = readCSV(r, emit, imp.reject)
+	default:
+		err = &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("unsupported import format '%s'", format)}
+	}
+
+	for i, batch := range pending {
+		if len(batch) > 0 {
+			workers[i] <- batch
+		}
+		close(workers[i])
+	}
+	wg.Wait()
+
+	app.invalidateCache(resourceName)
+
+	return &imp.report, err
+}
+
+func readJSONL(r io.Reader, emit func(importRow), reject func(importRow, *APIError)) *APIError {
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		b, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(b)) > 0 {
+			var raw map[string]interface{}
+			decoder := json.NewDecoder(bytes.NewReader(b))
+			decoder.UseNumber()
+			if e := decoder.Decode(&raw); e != nil {
+				reject(importRow{line: line}, &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("invalid json:%v", e.Error())})
+			} else {
+				emit(importRow{line: line, raw: raw})
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("read import data error:%v", err.Error())}
+		}
+	}
+}
+
+// 列数与表头不同的行记入失败, 不中断导入
+func readCSV(r io.Reader, emit func(importRow), reject func(importRow, *APIError)) *APIError {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("read import header error:%v", err.Error())}
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("read import data error:%v", err.Error())}
+		}
+
+		raw := make(map[string]interface{}, len(header))
+		for i, column := range header {
+			if i < len(record) && record[i] != "" {
+				raw[column] = record[i]
+			}
+		}
+		if len(record) != len(header) {
+			reject(importRow{line: line, raw: raw}, &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("wrong number of fields, got %d, want %d", len(record), len(header))})
+			continue
+		}
+		emit(importRow{line: line, raw: raw})
+	}
+}
+
+// 按列类型转换, 并还原 `a.b` 形式的嵌套字段
+func (imp *importer) coerce(raw map[string]interface{}) (map[string]interface{}, error) {
+	data := make(map[string]interface{}, len(raw))
+	for column, value := range raw {
+		v, err := coerceValue(value, imp.options.Columns[column])
+		if err != nil {
+			return nil, fmt.Errorf("column '%s': %v", column, err.Error())
+		}
+
+		parts := strings.Split(column, ".")
+		m := data
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := m[part].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				m[part] = child
+			}
+			m = child
+		}
+		m[parts[len(parts)-1]] = v
+	}
+	return data, nil
+}
+
+func coerceValue(value interface{}, typ ColumnType) (interface{}, error) {
+	s, isString := value.(string)
+	if !isString {
+		if typ == ColumnTypeGeoPoint {
+			if coords, ok := value.([]interface{}); ok && len(coords) == 2 {
+				s = fmt.Sprintf("%v,%v", coords[0], coords[1])
+				isString = true
+			}
+		}
+		if !isString {
+			return value, nil
+		}
+	}
+
+	switch typ {
+	case ColumnTypeNumber:
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, nil
+		}
+		return strconv.ParseFloat(s, 64)
+	case ColumnTypeBool:
+		return strconv.ParseBool(s)
+	case ColumnTypeDate:
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, err
+		}
+		return encodeDate(t), nil
+	case ColumnTypeGeoPoint:
+		parts := strings.Split(s, ",")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid geo point '%s'", s)
+		}
+		lng, err1 := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		lat, err2 := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid geo point '%s'", s)
+		}
+		point := NewCoordinate(lng, lat)
+		if err := point.Validate(); err != nil {
+			return nil, err
+		}
+		return Point{Coordinates: point}, nil
+	case ColumnTypeJSON:
+		var v interface{}
+		decoder := json.NewDecoder(strings.NewReader(s))
+		decoder.UseNumber()
+		err := decoder.Decode(&v)
+		return v, err
+	}
+	return s, nil
+}
+
+// 同一批次中 UpsertKey 相同的行分多次发送, 后面的行在前面的行写入后才能匹配到并更新
+func (imp *importer) send(batch []importRow) {
+	for len(batch) > 0 {
+		var next []importRow
+		batch, next = imp.splitDuplicates(batch)
+		imp.write(batch)
+		batch = next
+	}
+}
+
+// 按 UpsertKey 拆分, 返回每个值第一次出现的行及其余的行
+func (imp *importer) splitDuplicates(batch []importRow) ([]importRow, []importRow) {
+	if imp.options.UpsertKey == "" {
+		return batch, nil
+	}
+
+	var unique, duplicates []importRow
+	seen := map[string]bool{}
+	for _, row := range batch {
+		if v, ok := row.data[imp.options.UpsertKey]; ok {
+			key := upsertKey(v)
+			if seen[key] {
+				duplicates = append(duplicates, row)
+				continue
+			}
+			seen[key] = true
+		}
+		unique = append(unique, row)
+	}
+	return unique, duplicates
+}
+
+func (imp *importer) write(batch []importRow) {
+	existing := map[string]string{}
+	if imp.options.UpsertKey != "" {
+		var err *APIError
+		existing, err = imp.findExisting(batch)
+		if err != nil {
+			for _, row := range batch {
+				imp.reject(row, err)
+			}
+			return
+		}
+	}
+
+	requests := make([]batchRequest, len(batch))
+	for i, row := range batch {
+		requests[i] = batchRequest{Method: "POST", Path: "resources/" + imp.name, Body: row.data}
+		if v, ok := row.data[imp.options.UpsertKey]; ok {
+			if id, ok := existing[upsertKey(v)]; ok {
+				requests[i] = batchRequest{Method: "PUT", Path: "resources/" + imp.name + "/" + id, Body: row.data}
+			}
+		}
+	}
+
+	results, err := imp.app.sendBatch(requests)
+	if err != nil {
+		for _, row := range batch {
+			imp.reject(row, err)
+		}
+		return
+	}
+
+	imp.mu.Lock()
+	defer imp.mu.Unlock()
+	for i, result := range results {
+		if result.Error != nil {
+			imp.writeReject(batch[i], result.Error)
+			continue
+		}
+		if requests[i].Method == "PUT" {
+			imp.report.Updated++
+		} else {
+			imp.report.Created++
+		}
+	}
+}
+
+// 查找批次中 UpsertKey 已存在的数据, 返回 key值 => objectId
+func (imp *importer) findExisting(batch []importRow) (map[string]string, *APIError) {
+	key := imp.options.UpsertKey
+	var values []interface{}
+	for _, row := range batch {
+		if v, ok := row.data[key]; ok {
+			values = append(values, v)
+		}
+	}
+
+	result := map[string]string{}
+	if len(values) == 0 {
+		return result, nil
+	}
+
+	objects, _, err := imp.app.NewQuery(imp.name).In(key, values).Select(key).Take(len(values)).Find()
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objects {
+		result[upsertKey(obj.Get(key))] = obj.ObjectId
+	}
+	return result, nil
+}
+
+// 用于匹配 UpsertKey 的字符串, 导入的 int64, json.Number 与服务器返回的 float64 数值相同时结果相同
+func upsertKey(v interface{}) string {
+	var f float64
+	switch n := v.(type) {
+	case json.Number:
+		parsed, err := n.Float64()
+		if err != nil {
+			return "n:" + n.String()
+		}
+		f = parsed
+	case float64:
+		f = n
+	case float32:
+		f = float64(n)
+	case int:
+		f = float64(n)
+	case int64:
+		f = float64(n)
+	case int32:
+		f = float64(n)
+	case string:
+		return "s:" + n
+	default:
+		return fmt.Sprintf("%T:%v", v, v)
+	}
+	return "n:" + strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func (imp *importer) reject(row importRow, err *APIError) {
+	imp.mu.Lock()
+	defer imp.mu.Unlock()
+	imp.writeReject(row, err)
+}
+
+func (imp *importer) writeReject(row importRow, err *APIError) {
+	imp.report.Rejected++
+	if imp.options.RejectWriter == nil {
+		return
+	}
+	b, e := json.Marshal(map[string]interface{}{"line": row.line, "row": row.raw, "error": err})
+	if e != nil {
+		return
+	}
+	imp.options.RejectWriter.Write(append(b, '\n'))
+}
@@ -0,0 +1,106 @@
+package skynology_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sky "github.com/skynology/go-sdk"
+)
+
+// 在内存中模拟查询及批量写入的 handler
+type memoryHandler struct {
+	mu      sync.Mutex
+	objects map[string]map[string]interface{}
+}
+
+func (h *memoryHandler) SendRequest(params sky.HandlerRequestParams) (map[string]interface{}, *sky.APIError) {
+	// 模拟网络延迟, 让并发的批次交错执行
+	time.Sleep(time.Millisecond)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	u, _ := url.Parse(params.URL)
+	if params.Method == "GET" {
+		var where map[string]map[string][]interface{}
+		json.Unmarshal([]byte(u.Query().Get("where")), &where)
+		var results []interface{}
+		for id, obj := range h.objects {
+			for _, v := range where["key"]["$in"] {
+				if obj["key"] == v {
+					results = append(results, map[string]interface{}{"objectId": id, "key": obj["key"]})
+				}
+			}
+		}
+		return map[string]interface{}{"results": results}, nil
+	}
+
+	var body struct {
+		Requests []struct {
+			Method string                 `json:"method"`
+			Path   string                 `json:"path"`
+			Body   map[string]interface{} `json:"body"`
+		} `json:"requests"`
+	}
+	b, _ := json.Marshal(params.Data)
+	json.Unmarshal(b, &body)
+
+	var results []interface{}
+	for _, r := range body.Requests {
+		switch r.Method {
+		case "POST":
+			id := fmt.Sprint(len(h.objects) + 1)
+			h.objects[id] = r.Body
+			results = append(results, map[string]interface{}{"success": map[string]interface{}{"objectId": id}})
+		case "PUT":
+			id := r.Path[strings.LastIndex(r.Path, "/")+1:]
+			h.objects[id] = r.Body
+			results = append(results, map[string]interface{}{"success": map[string]interface{}{}})
+		}
+	}
+	return map[string]interface{}{"results": results}, nil
+}
+
+func TestImportUpsertConcurrent(t *testing.T) {
+	handler := &memoryHandler{objects: make(map[string]map[string]interface{})}
+	app := sky.NewApp("app", "key")
+	app.SetRequestHandler(handler)
+
+	// 5个不同的 key 分布在多个批次中
+	var lines []string
+	for i := 0; i < 100; i++ {
+		lines = append(lines, fmt.Sprintf(`{"key":%d,"n":%d}`, i%5, i))
+	}
+
+	report, err := app.Import("Post", strings.NewReader(strings.Join(lines, "\n")), sky.ImportFormatJSONL, &sky.ImportOptions{
+		BatchSize:   3,
+		Concurrency: 4,
+		UpsertKey:   "key",
+	})
+	if err != nil {
+		t.Fatalf("import error: %v", err.String())
+	}
+	if len(handler.objects) != 5 || report.Created != 5 || report.Updated != 95 || report.Rejected != 0 {
+		t.Fatalf("got %d objects, report %+v", len(handler.objects), report)
+	}
+}
+
+func TestImportRejectsRaggedCSV(t *testing.T) {
+	handler := &memoryHandler{objects: make(map[string]map[string]interface{})}
+	app := sky.NewApp("app", "key")
+	app.SetRequestHandler(handler)
+
+	var rejects strings.Builder
+	report, err := app.Import("Post", strings.NewReader("key,n\n1,2\n3\n4,5,6\n7,8\n"), sky.ImportFormatCSV, &sky.ImportOptions{RejectWriter: &rejects})
+	if err != nil {
+		t.Fatalf("import error: %v", err.String())
+	}
+	if report.Created != 2 || report.Rejected != 2 || strings.Count(rejects.String(), "\n") != 2 {
+		t.Fatalf("report %+v, rejects %q", report, rejects.String())
+	}
+}
@@ -0,0 +1,189 @@
+package skynology
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 在 Each 的回调中返回此错误可提前结束遍历, Each 不会返回错误
+var ErrStopIteration = errors.New("stop iteration")
+
+// 默认每页数量
+const defaultIteratorPageSize = 100
+
+// 遍历位置, 即最后一条数据的游标字段值及 objectId
+// 可保存下来, 之后通过 StartAfter 继续遍历
+type QueryCursor struct {
+	Value    time.Time `json:"value"`
+	ObjectId string    `json:"objectId"`
+}
+
+func (c QueryCursor) IsZero() bool {
+	return c.ObjectId == ""
+}
+
+// 自动分页遍历查询结果
+// 使用 游标字段 + objectId 作为游标, 而不是递增 skip,
+// 因此遍历过程中数据有增删也不会重复或遗漏已存在的数据
+type QueryIterator struct {
+	query       *Query
+	cursorField string
+	pageSize    int
+	page        []Object
+	index       int
+	current     *Object
+	cursor      QueryCursor
+	done        bool
+	err         *APIError
+}
+
+// 按 createdAt 遍历查询结果
+// 遍历时会忽略查询的 order, skip 及 take 设置
+// 有 Near 条件时服务器按距离排序, 无法按游标分页, 遍历会直接返回错误
+func (query *Query) Iterator() *QueryIterator {
+	return newQueryIterator(query, "createdAt")
+}
+
+func newQueryIterator(query *Query, cursorField string) *QueryIterator {
+	it := &QueryIterator{
+		query:       query.clone(),
+		cursorField: cursorField,
+		pageSize:    defaultIteratorPageSize,
+		err:         query.err,
+	}
+	if it.err == nil && query.hasNear() {
+		it.err = &APIError{Code: ERROR_CODE_CLIENT, Error: "cannot iterate a query with $near, results are sorted by distance"}
+	}
+	return it
+}
+
+// 设置每页数量, 默认100
+func (it *QueryIterator) PageSize(size int) *QueryIterator {
+	if size > 0 {
+		it.pageSize = size
+	}
+	return it
+}
+
+// 从指定位置之后开始遍历
+func (it *QueryIterator) StartAfter(cursor QueryCursor) *QueryIterator {
+	it.cursor = cursor
+	return it
+}
+
+// 移到下一条数据, 没有更多数据或出错时返回 false
+func (it *QueryIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	if it.index >= len(it.page) {
+		if it.done {
+			it.current = nil
+			return false
+		}
+		if err := it.fetch(); err != nil {
+			it.err = err
+			return false
+		}
+		if len(it.page) == 0 {
+			it.current = nil
+			return false
+		}
+	}
+
+	obj := &it.page[it.index]
+	it.index++
+
+	value := obj.GetTime(it.cursorField)
+	if value.IsZero() || obj.ObjectId == "" {
+		it.err = &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("object has no '%s' or objectId, cannot iterate", it.cursorField)}
+		return false
+	}
+	it.cursor = QueryCursor{Value: value, ObjectId: obj.ObjectId}
+	it.current = obj
+
+	return true
+}
+
+// 当前数据
+func (it *QueryIterator) Object() *Object {
+	return it.current
+}
+
+// 当前遍历位置
+func (it *QueryIterator) Cursor() QueryCursor {
+	return it.cursor
+}
+
+// 遍历过程中的错误
+func (it *QueryIterator) Err() *APIError {
+	return it.err
+}
+
+// 结束遍历
+func (it *QueryIterator) Stop() {
+	it.done = true
+	it.page = nil
+	it.index = 0
+}
+
+func (it *QueryIterator) fetch() *APIError {
+	query := it.query.clone()
+	query._skip = 0
+	query._take = it.pageSize
+	query._count = false
+	query.order = []string{it.cursorField, "objectId"}
+	if len(query.field) > 0 {
+		query.field = append(query.field, it.cursorField, "objectId")
+	}
+
+	if !it.cursor.IsZero() {
+		value := encodeDate(it.cursor.Value)
+		after := map[string]interface{}{
+			"$or": []interface{}{
+				map[string]interface{}{it.cursorField: map[string]interface{}{"$gt": value}},
+				map[string]interface{}{it.cursorField: value, "objectId": map[string]interface{}{"$gt": it.cursor.ObjectId}},
+			},
+		}
+		if len(query.where) > 0 {
+			after = map[string]interface{}{"$and": []interface{}{query.where, after}}
+		}
+		query.where = after
+	}
+
+	results, _, err := query.Find()
+	if err != nil {
+		return err
+	}
+
+	it.page = results
+	it.index = 0
+	if len(results) < it.pageSize {
+		it.done = true
+	}
+
+	return nil
+}
+
+// 遍历所有查询结果
+// 回调返回 ErrStopIteration 时结束遍历, 返回其他错误时结束遍历并返回该错误
+func (query *Query) Each(fn func(*Object) error) *APIError {
+	return query.Iterator().Each(fn)
+}
+
+// 遍历剩余的全部数据, 可先通过 PageSize 设置每页数量
+func (it *QueryIterator) Each(fn func(*Object) error) *APIError {
+	for it.Next() {
+		if err := fn(it.Object()); err != nil {
+			it.Stop()
+			if err == ErrStopIteration {
+				return nil
+			}
+			return &APIError{Code: ERROR_CODE_CLIENT, Error: err.Error()}
+		}
+	}
+
+	return it.Err()
+}
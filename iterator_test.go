@@ -0,0 +1,25 @@
+package skynology_test
+
+import (
+	"testing"
+
+	sky "github.com/skynology/go-sdk"
+)
+
+func TestIteratorRefusesNear(t *testing.T) {
+	handler := &recordingHandler{response: map[string]interface{}{"results": []interface{}{}}}
+	app := sky.NewApp("app", "key")
+	app.SetRequestHandler(handler)
+
+	query := app.NewQuery("Shop").Near("location", sky.NewCoordinate(116.4, 39.9), 1000)
+	it := query.Iterator()
+	if it.Next() || it.Err() == nil {
+		t.Fatal("iterating a $near query should fail")
+	}
+	if err := query.Each(func(*sky.Object) error { return nil }); err == nil {
+		t.Fatal("Each on a $near query should fail")
+	}
+	if len(handler.requests) != 0 {
+		t.Fatalf("sent %d requests, want none", len(handler.requests))
+	}
+}
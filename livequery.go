@@ -0,0 +1,319 @@
+package skynology
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 实时查询事件类型
+type LiveQueryEventType string
+
+const (
+	// 新建了满足条件的数据
+	LiveQueryEventCreate LiveQueryEventType = "create"
+	// 满足条件的数据被更新, 更新后仍满足条件
+	LiveQueryEventUpdate LiveQueryEventType = "update"
+	// 满足条件的数据被删除
+	LiveQueryEventDelete LiveQueryEventType = "delete"
+	// 数据更新后开始满足条件
+	LiveQueryEventEnter LiveQueryEventType = "enter"
+	// 数据更新后不再满足条件
+	LiveQueryEventLeave LiveQueryEventType = "leave"
+)
+
+// 实时查询事件
+type LiveQueryEvent struct {
+	Type   LiveQueryEventType
+	Object *Object
+}
+
+const (
+	liveQueryMinReconnectDelay = 500 * time.Millisecond
+	liveQueryMaxReconnectDelay = 30 * time.Second
+	// 建立连接, 认证及订阅的超时时间
+	liveQueryHandshakeTimeout = 10 * time.Second
+	liveQueryWriteTimeout     = 10 * time.Second
+	liveQueryPingInterval     = 30 * time.Second
+	// 超过此时间没有收到任何消息或 pong 时视为连接已断开
+	liveQueryReadTimeout = 2 * liveQueryPingInterval
+	liveQueryEventBuffer = 64
+)
+
+// 实时查询订阅
+// 连接断开后会自动重连并重新订阅, 直到调用 Close
+type LiveQuerySubscription struct {
+	query  *Query
+	events chan LiveQueryEvent
+	errors chan *APIError
+	done   chan struct{}
+
+	mu     sync.Mutex
+	conn   *websocket.Conn
+	closed bool
+}
+
+// 服务器发送的消息
+type liveQueryMessage struct {
+	Op        string                 `json:"op"`
+	RequestId int                    `json:"requestId,omitempty"`
+	Object    map[string]interface{} `json:"object,omitempty"`
+	Code      int                    `json:"code,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+// 订阅查询结果的变化
+// 使用 app 的签名及 SessionToken 认证, 只使用查询的 where 及 Select 设置
+func (query *Query) Subscribe() (*LiveQuerySubscription, *APIError) {
+	if query.err != nil {
+		return nil, query.err
+	}
+
+	sub := &LiveQuerySubscription{
+		query:  query.clone(),
+		events: make(chan LiveQueryEvent, liveQueryEventBuffer),
+		errors: make(chan *APIError, 1),
+		done:   make(chan struct{}),
+	}
+
+	conn, err := sub.connect()
+	if err != nil {
+		return nil, err
+	}
+	go sub.run(conn)
+
+	return sub, nil
+}
+
+// 事件, 调用 Close 后会被关闭
+func (sub *LiveQuerySubscription) Events() <-chan LiveQueryEvent {
+	return sub.events
+}
+
+// 连接或服务器返回的错误, 出错后会自动重连
+// 只保留最近未读取的一个错误
+func (sub *LiveQuerySubscription) Errors() <-chan *APIError {
+	return sub.errors
+}
+
+// 取消订阅并关闭连接
+func (sub *LiveQuerySubscription) Close() {
+	sub.mu.Lock()
+	if sub.closed {
+		sub.mu.Unlock()
+		return
+	}
+	sub.closed = true
+	conn := sub.conn
+	sub.mu.Unlock()
+
+	close(sub.done)
+	if conn != nil {
+		conn.Close()
+	}
+}
+
+// 连接, 认证并订阅
+func (sub *LiveQuerySubscription) connect() (*websocket.Conn, *APIError) {
+	app := sub.query.app
+	sign, err := app.getRequestSign()
+	if err != nil {
+		return nil, &APIError{Code: ERROR_CODE_CLIENT, Error: err.Error()}
+	}
+
+	dialer := websocket.Dialer{Proxy: http.ProxyFromEnvironment, HandshakeTimeout: liveQueryHandshakeTimeout}
+	conn, _, err := dialer.Dial(liveQueryURL(app.baseURL), nil)
+	if err != nil {
+		return nil, &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("cannot connect to live query server. %v", err.Error())}
+	}
+	conn.SetReadDeadline(time.Now().Add(liveQueryHandshakeTimeout))
+
+	connect := map[string]interface{}{
+		"op":            "connect",
+		"applicationId": app.ApplicationId,
+		"sign":          sign,
+		"clientVersion": fmt.Sprintf("go-%v", SDK_VERSION),
+	}
+	if app.SessionToken != "" {
+		connect["sessionToken"] = app.SessionToken
+	}
+	if apiErr := sub.request(conn, connect, "connected"); apiErr != nil {
+		conn.Close()
+		return nil, apiErr
+	}
+
+	q := map[string]interface{}{
+		"resourceName": sub.query.ResourceName,
+		"where":        sub.query.where,
+	}
+	if len(sub.query.field) > 0 {
+		q["select"] = sub.query.field
+	}
+	subscribe := map[string]interface{}{"op": "subscribe", "requestId": 1, "query": q}
+	if apiErr := sub.request(conn, subscribe, "subscribed"); apiErr != nil {
+		conn.Close()
+		return nil, apiErr
+	}
+
+	// 收到消息或 pong 时延长读取期限, 期限内没有收到时连接已断开
+	conn.SetReadDeadline(time.Now().Add(liveQueryReadTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(liveQueryReadTimeout))
+	})
+
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.closed {
+		conn.Close()
+		return nil, &APIError{Code: ERROR_CODE_CLIENT, Error: "subscription closed"}
+	}
+	sub.conn = conn
+
+	return conn, nil
+}
+
+// 发送消息并等待指定的回复
+func (sub *LiveQuerySubscription) request(conn *websocket.Conn, data map[string]interface{}, expect string) *APIError {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("marshal json data error:%v", err.Error())}
+	}
+	conn.SetWriteDeadline(time.Now().Add(liveQueryWriteTimeout))
+	if err := conn.WriteMessage(websocket.TextMessage, b); err != nil {
+		return &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("send live query message error:%v", err.Error())}
+	}
+
+	msg, apiErr := readLiveQueryMessage(conn)
+	if apiErr != nil {
+		return apiErr
+	}
+	if msg.Op == "error" {
+		return &APIError{Code: msg.Code, Error: msg.Error}
+	}
+	if msg.Op != expect {
+		return &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("unexpected live query message '%s', want '%s'", msg.Op, expect)}
+	}
+	return nil
+}
+
+// 读取事件, 断开后重连, 直到 Close
+func (sub *LiveQuerySubscription) run(conn *websocket.Conn) {
+	defer close(sub.events)
+
+	delay := liveQueryMinReconnectDelay
+	for {
+		stopPing := make(chan struct{})
+		go keepAlive(conn, stopPing)
+		sub.readEvents(conn)
+		close(stopPing)
+		conn.Close()
+
+		// 重连, 失败时等待时间逐渐增加
+		for {
+			select {
+			case <-sub.done:
+				return
+			case <-time.After(delay):
+			}
+
+			var err *APIError
+			conn, err = sub.connect()
+			if err == nil {
+				delay = liveQueryMinReconnectDelay
+				break
+			}
+			sub.reportError(err)
+			if delay *= 2; delay > liveQueryMaxReconnectDelay {
+				delay = liveQueryMaxReconnectDelay
+			}
+		}
+	}
+}
+
+func (sub *LiveQuerySubscription) readEvents(conn *websocket.Conn) {
+	for {
+		msg, err := readLiveQueryMessage(conn)
+		if err != nil {
+			select {
+			case <-sub.done:
+			default:
+				sub.reportError(err)
+			}
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(liveQueryReadTimeout))
+
+		switch LiveQueryEventType(msg.Op) {
+		case LiveQueryEventCreate, LiveQueryEventUpdate, LiveQueryEventDelete, LiveQueryEventEnter, LiveQueryEventLeave:
+			event := LiveQueryEvent{
+				Type:   LiveQueryEventType(msg.Op),
+				Object: sub.query.app.NewObjectWithData(sub.query.ResourceName, msg.Object),
+			}
+			select {
+			case sub.events <- event:
+			case <-sub.done:
+				return
+			}
+		case "error":
+			sub.reportError(&APIError{Code: msg.Code, Error: msg.Error})
+		}
+	}
+}
+
+// 保留最近的错误, 不阻塞
+func (sub *LiveQuerySubscription) reportError(err *APIError) {
+	select {
+	case <-sub.errors:
+	default:
+	}
+	select {
+	case sub.errors <- err:
+	default:
+	}
+}
+
+// 定时发送 ping, 没有收到 pong 时读取超时, readEvents 返回后重连
+func keepAlive(conn *websocket.Conn, stop chan struct{}) {
+	ticker := time.NewTicker(liveQueryPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(liveQueryWriteTimeout)); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func readLiveQueryMessage(conn *websocket.Conn) (*liveQueryMessage, *APIError) {
+	_, b, err := conn.ReadMessage()
+	if err != nil {
+		return nil, &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("read live query message error:%v", err.Error())}
+	}
+
+	var msg liveQueryMessage
+	if err := json.Unmarshal(b, &msg); err != nil {
+		return nil, &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("parse live query message error:%v", err.Error())}
+	}
+	if msg.Object == nil {
+		msg.Object = make(map[string]interface{})
+	}
+	return &msg, nil
+}
+
+// 由 baseURL 得到实时查询地址, 如 http://host/api/1.0 => ws://host/api/1.0/live
+func liveQueryURL(baseURL string) string {
+	if strings.HasPrefix(baseURL, "https://") {
+		return "wss://" + strings.TrimPrefix(baseURL, "https://") + "/live"
+	}
+	return "ws://" + strings.TrimPrefix(baseURL, "http://") + "/live"
+}
@@ -0,0 +1,77 @@
+package skynology_test
+
+import (
+	"testing"
+	"time"
+
+	sky "github.com/skynology/go-sdk"
+	"github.com/skynology/go-sdk/livequerytest"
+)
+
+func TestLiveQueryReconnect(t *testing.T) {
+	server := livequerytest.NewServer()
+	defer server.Close()
+
+	app := sky.NewApp("app", "key")
+	app.SetBaseURL(server.URL)
+
+	sub, err := app.NewQuery("Post").Equal("title", "hello").Subscribe()
+	if err != nil {
+		t.Fatalf("subscribe error: %v", err.String())
+	}
+	defer sub.Close()
+
+	subs := server.Subscriptions()
+	if len(subs) != 1 || subs[0].ApplicationId != "app" || subs[0].ResourceName != "Post" {
+		t.Fatalf("unexpected subscriptions %+v", subs)
+	}
+
+	expectEvent(t, sub, server, sky.LiveQueryEventCreate, "1")
+
+	// 断开后应自动重连并重新订阅
+	server.DropConnections()
+	if !server.WaitForSubscriptions(1, 5*time.Second) {
+		t.Fatal("subscription was not restored after reconnect")
+	}
+	expectEvent(t, sub, server, sky.LiveQueryEventUpdate, "2")
+
+	sub.Close()
+	select {
+	case _, ok := <-sub.Events():
+		if ok {
+			t.Fatal("unexpected event after close")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("events channel was not closed")
+	}
+	if !waitForNoSubscriptions(server, 5*time.Second) {
+		t.Fatal("connection was not closed")
+	}
+}
+
+func expectEvent(t *testing.T, sub *sky.LiveQuerySubscription, server *livequerytest.Server, typ sky.LiveQueryEventType, objectId string) {
+	t.Helper()
+
+	if n := server.Publish(string(typ), "Post", map[string]interface{}{"objectId": objectId, "title": "hello"}); n != 1 {
+		t.Fatalf("published to %d subscriptions, want 1", n)
+	}
+	select {
+	case event := <-sub.Events():
+		if event.Type != typ || event.Object.ObjectId != objectId || event.Object.GetString("title") != "hello" {
+			t.Fatalf("unexpected event %v %+v", event.Type, event.Object)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no %s event received", typ)
+	}
+}
+
+func waitForNoSubscriptions(server *livequerytest.Server, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if len(server.Subscriptions()) == 0 {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
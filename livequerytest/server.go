@@ -0,0 +1,209 @@
+// 本地实时查询测试服务器, 用于离线测试 Query.Subscribe
+//
+//	server := livequerytest.NewServer()
+//	defer server.Close()
+//	app.SetBaseURL(server.URL)
+//	sub, err := app.NewQuery("Post").Subscribe()
+//	server.Publish("create", "Post", map[string]interface{}{"objectId": "1"})
+package livequerytest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{}
+
+type Server struct {
+	// 传给 App.SetBaseURL 的地址
+	URL string
+
+	srv     *httptest.Server
+	mu      sync.Mutex
+	clients map[*client]bool
+	changed chan struct{}
+}
+
+type client struct {
+	conn          *websocket.Conn
+	applicationId string
+	sessionToken  string
+	subscriptions map[int]string
+}
+
+// 客户端订阅的信息
+type Subscription struct {
+	ApplicationId string
+	SessionToken  string
+	ResourceName  string
+}
+
+func NewServer() *Server {
+	s := &Server{
+		clients: make(map[*client]bool),
+		changed: make(chan struct{}, 1),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	s.URL = s.srv.URL
+	return s
+}
+
+func (s *Server) Close() {
+	s.DropConnections()
+	s.srv.Close()
+}
+
+// 向订阅了指定资源的客户端发送事件, 返回发送的数量
+// event 为 create, update, delete, enter 或 leave
+func (s *Server) Publish(event string, resourceName string, object map[string]interface{}) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for c := range s.clients {
+		for requestId, name := range c.subscriptions {
+			if name != resourceName {
+				continue
+			}
+			msg := map[string]interface{}{"op": event, "requestId": requestId, "object": object}
+			if writeJSON(c.conn, msg) == nil {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// 断开所有客户端, 用于测试自动重连
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for c := range s.clients {
+		c.conn.Close()
+		delete(s.clients, c)
+	}
+	s.notify()
+}
+
+// 当前所有订阅
+func (s *Server) Subscriptions() []Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []Subscription
+	for c := range s.clients {
+		for _, name := range c.subscriptions {
+			result = append(result, Subscription{ApplicationId: c.applicationId, SessionToken: c.sessionToken, ResourceName: name})
+		}
+	}
+	return result
+}
+
+// 等待订阅数量达到 n, 超时返回 false
+func (s *Server) WaitForSubscriptions(n int, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		if len(s.Subscriptions()) >= n {
+			return true
+		}
+		select {
+		case <-s.changed:
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/live" {
+		http.NotFound(w, r)
+		return
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &client{conn: conn, subscriptions: make(map[int]string)}
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, c)
+		s.notify()
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	connected := false
+	for {
+		_, b, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg struct {
+			Op            string `json:"op"`
+			RequestId     int    `json:"requestId"`
+			ApplicationId string `json:"applicationId"`
+			Sign          string `json:"sign"`
+			SessionToken  string `json:"sessionToken"`
+			Query         struct {
+				ResourceName string `json:"resourceName"`
+			} `json:"query"`
+		}
+		if err := json.Unmarshal(b, &msg); err != nil {
+			writeError(conn, "invalid message")
+			continue
+		}
+
+		s.mu.Lock()
+		switch {
+		case msg.Op == "connect":
+			if msg.ApplicationId == "" || msg.Sign == "" {
+				writeError(conn, "missing applicationId or sign")
+				break
+			}
+			c.applicationId = msg.ApplicationId
+			c.sessionToken = msg.SessionToken
+			connected = true
+			s.clients[c] = true
+			writeJSON(conn, map[string]interface{}{"op": "connected"})
+		case !connected:
+			writeError(conn, "not connected")
+		case msg.Op == "subscribe":
+			c.subscriptions[msg.RequestId] = msg.Query.ResourceName
+			writeJSON(conn, map[string]interface{}{"op": "subscribed", "requestId": msg.RequestId})
+		case msg.Op == "unsubscribe":
+			delete(c.subscriptions, msg.RequestId)
+			writeJSON(conn, map[string]interface{}{"op": "unsubscribed", "requestId": msg.RequestId})
+		default:
+			writeError(conn, "unknown op")
+		}
+		s.notify()
+		s.mu.Unlock()
+	}
+}
+
+func (s *Server) notify() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+func writeError(conn *websocket.Conn, message string) error {
+	return writeJSON(conn, map[string]interface{}{"op": "error", "code": -1, "error": message})
+}
+
+func writeJSON(conn *websocket.Conn, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(websocket.TextMessage, b)
+}
@@ -0,0 +1,267 @@
+package skynology
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 默认的最大url长度, 超过时改用POST查询
+const DEFAULT_MAX_URL_LENGTH = 4096
+
+// 设置最大url长度, 查询url超过此长度时改用POST查询, 小于等于0时不限制
+func (app *App) SetMaxURLLength(length int) {
+	app.maxURLLength = length
+}
+
+// 发送查询请求
+// url 过长时改用POST查询接口, 服务器不支持时将最大的 $in 列表分段查询后合并结果
+func (query *Query) request(url string, objectId string) (map[string]interface{}, *APIError) {
+	maxLength := query.app.maxURLLength
+	if maxLength <= 0 || len(url) <= maxLength {
+		return query.send("GET", url, nil)
+	}
+
+	body := query.postBody()
+	if objectId != "" {
+		body["objectId"] = objectId
+	}
+	m, err := query.send("POST", fmt.Sprintf("%s/query/%s", query.app.baseURL, query.ResourceName), body)
+	if err != nil && isEndpointMissing(err) && objectId == "" {
+		return query.findInChunks()
+	}
+	return m, err
+}
+
+// POST查询的内容, 与查询字符串的参数一致
+func (query *Query) postBody() map[string]interface{} {
+	body := map[string]interface{}{
+		"where": query.where,
+		"take":  query._take,
+	}
+	if query._count {
+		body["count"] = 1
+	}
+	if len(query.order) > 0 && !query.hasNear() {
+		body["order"] = strings.Join(query.order, ",")
+	}
+	if len(query.field) > 0 {
+		body["select"] = strings.Join(query.field, ",")
+	}
+	if query._skip > 0 {
+		body["skip"] = query._skip
+	}
+	if len(query.include) > 0 {
+		body["include"] = strings.Join(query.include, ",")
+	}
+	if query.highlight {
+		body["highlight"] = 1
+	}
+	return body
+}
+
+// 将最大的 $in 列表分段, 每段单独查询, 再按 order 合并并应用 skip/take
+// 每段都返回了全部匹配的数据时 count 为去重后的数量, 否则为各段 count 之和,
+// 字段为数组且匹配多个分段时会被重复计数
+func (query *Query) findInChunks() (map[string]interface{}, *APIError) {
+	field, values := query.largestInList()
+	if field == "" {
+		return nil, &APIError{Code: ERROR_CODE_CLIENT, Error: "query url is too long and server does not support POST query"}
+	}
+
+	// 分段越小url越短, 不断减半直到满足长度限制
+	size := len(values)
+	for ; size > 0; size /= 2 {
+		url, err := query.chunkURL(field, values[:size])
+		if err != nil {
+			return nil, err
+		}
+		if len(url) <= query.app.maxURLLength {
+			break
+		}
+	}
+	if size == 0 {
+		return nil, &APIError{Code: ERROR_CODE_CLIENT, Error: "query url is too long even with a single $in value"}
+	}
+
+	var results []interface{}
+	seen := map[string]bool{}
+	count := 0
+	complete := true
+	for start := 0; start < len(values); start += size {
+		end := start + size
+		if end > len(values) {
+			end = len(values)
+		}
+
+		url, err := query.chunkURL(field, values[start:end])
+		if err != nil {
+			return nil, err
+		}
+		m, err := query.send("GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		items, _ := m["results"].([]interface{})
+		count += GetInt(m["count"])
+		if GetInt(m["count"]) > len(items) {
+			complete = false
+		}
+
+		for _, item := range items {
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if id, _ := obj["objectId"].(string); id != "" {
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+			}
+			results = append(results, obj)
+		}
+	}
+
+	if complete {
+		count = len(results)
+	}
+
+	if !query.hasNear() {
+		sortResults(results, query.order)
+	}
+	if query._skip >= len(results) {
+		results = nil
+	} else {
+		results = results[query._skip:]
+	}
+	if len(results) > query._take {
+		results = results[:query._take]
+	}
+
+	m := map[string]interface{}{"results": results}
+	if query._count {
+		m["count"] = float64(count)
+	}
+	return m, nil
+}
+
+// 返回值最多的 $in 条件, 已去重
+func (query *Query) largestInList() (string, []interface{}) {
+	var field string
+	var values []interface{}
+	for k, v := range query.where {
+		cond, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		list, ok := cond["$in"].([]interface{})
+		if !ok || len(list) <= len(values) {
+			continue
+		}
+		field, values = k, list
+	}
+
+	var unique []interface{}
+	seen := map[string]bool{}
+	for _, v := range values {
+		key := fmt.Sprintf("%T:%v", v, v)
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, v)
+		}
+	}
+	return field, unique
+}
+
+// 只查询部分 $in 值的url, 取前 skip+take 条以便合并后分页
+func (query *Query) chunkURL(field string, values []interface{}) (string, *APIError) {
+	q := query.clone()
+	q.where[field].(map[string]interface{})["$in"] = values
+	q._take = query._skip + query._take
+	q._skip = 0
+	search, err := q.getQueryString()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/resources/%s?%s", q.app.baseURL, q.ResourceName, search), nil
+}
+
+// 按 order 排序, 字段前有 `-` 为倒序
+func sortResults(results []interface{}, order []string) {
+	if len(order) == 0 {
+		return
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		a, _ := results[i].(map[string]interface{})
+		b, _ := results[j].(map[string]interface{})
+		for _, field := range order {
+			desc := strings.HasPrefix(field, "-")
+			field = strings.TrimPrefix(field, "-")
+			c := compareValues(lookupPath(a, field), lookupPath(b, field))
+			if c == 0 {
+				continue
+			}
+			if desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+// 按 `a.b` 形式的路径取值
+func lookupPath(data map[string]interface{}, path string) interface{} {
+	var v interface{} = data
+	for _, part := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[part]
+	}
+	return v
+}
+
+// 比较两个值, nil 最小, 类型不同时按字符串比较
+func compareValues(a, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		}
+		return 1
+	}
+
+	switch av := a.(type) {
+	case float64:
+		if bv, ok := b.(float64); ok {
+			switch {
+			case av < bv:
+				return -1
+			case av > bv:
+				return 1
+			}
+			return 0
+		}
+	case bool:
+		if bv, ok := b.(bool); ok {
+			switch {
+			case av == bv:
+				return 0
+			case !av:
+				return -1
+			}
+			return 1
+		}
+	case map[string]interface{}:
+		// Date 类型按 iso 字符串比较
+		if bv, ok := b.(map[string]interface{}); ok && av["__type"] == "Date" && bv["__type"] == "Date" {
+			return strings.Compare(GetString(av["iso"]), GetString(bv["iso"]))
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
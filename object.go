@@ -213,35 +213,14 @@ func (obj *Object) setAccessControl(key string, typ AccessControlType, value boo
 }
 
 // 检查指定用户和角色是否对当前对象有权限
+// typ 为 "read" 或 "write", 规则与 ACLChecker 相同
 func (obj *Object) CheckACL(userId string, roles []string, typ string) bool {
-	acl, err := NewACL(obj.GetMap("ACL"))
-	if err != nil {
+	t, ok := parseAccessControlType(typ)
+	if !ok {
 		return false
 	}
 
-	typ = strings.ToLower(typ)
-
-	for k, v := range acl {
-		if k == userId {
-			if typ == "read" && v.Read {
-				return true
-			}
-			if typ == "write" && v.Write {
-				return true
-			}
-		}
-		for _, role := range roles {
-			if k == ("role:" + role) {
-				if typ == "read" && v.Read {
-					return true
-				}
-				if typ == "write" && v.Write {
-					return true
-				}
-			}
-		}
-	}
-	return false
+	return NewACLChecker(userId, roles, false).Check(parseObjectACL(obj), t)
 }
 
 func (obj *Object) Save() (bool, *APIError) {
@@ -268,6 +247,7 @@ func (obj *Object) Save() (bool, *APIError) {
 		return false, err
 	}
 
+	obj.app.invalidateCache(obj.ResourceName)
 	obj.initData(m)
 
 	return true, nil
@@ -280,6 +260,8 @@ func (obj *Object) Delete() (bool, *APIError) {
 		return false, err
 	}
 
+	obj.app.invalidateCache(obj.ResourceName)
+
 	obj.clear()
 
 	return true, nil
@@ -306,7 +288,7 @@ func (obj *Object) initData(data map[string]interface{}) {
 		}
 	}
 
-	if ud, ok := data["createdAt"]; ok {
+	if ud, ok := data["updatedAt"]; ok {
 		if t, err := time.Parse(time.RFC3339Nano, ud.(string)); err == nil {
 			obj.UpdatedAt = t
 		}
@@ -1,61 +1,105 @@
 package skynology
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// 若该字段已有其他操作符条件, 将在执行查询时返回错误
 func (query *Query) Equal(field string, value interface{}) *Query {
+	if cond, ok := query.where[field].(map[string]interface{}); ok && isOperatorMap(cond) {
+		query.err = &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("cannot combine Equal with other constraints on field '%s'", field)}
+		return query
+	}
 	query.where[field] = value
 	return query
 }
 
 func (query *Query) NotEqual(field string, value interface{}) *Query {
-	query.where[field] = map[string]interface{}{"$ne": value}
-	return query
+	return query.addCondition(field, "$ne", value)
 }
 
-func (query *Query) LessThan(field string, value float64) *Query {
-	query.where[field] = map[string]interface{}{"$lt": value}
-	return query
+// 比较条件支持数字, 字符串及 time.Time 类型
+// time.Time 会转换为服务器的 Date 类型
+func (query *Query) LessThan(field string, value interface{}) *Query {
+	return query.addComparison(field, "$lt", value)
 }
-func (query *Query) LessThanOrEqual(field string, value float64) *Query {
-	query.where[field] = map[string]interface{}{"$lte": value}
-	return query
+func (query *Query) LessThanOrEqual(field string, value interface{}) *Query {
+	return query.addComparison(field, "$lte", value)
 }
-func (query *Query) GreaterThan(field string, value float64) *Query {
-	query.where[field] = map[string]interface{}{"$gt": value}
-	return query
+func (query *Query) GreaterThan(field string, value interface{}) *Query {
+	return query.addComparison(field, "$gt", value)
+}
+func (query *Query) GreaterThanOrEqual(field string, value interface{}) *Query {
+	return query.addComparison(field, "$gte", value)
+}
+
+// 字段值在 lo 和 hi 之间, 包括 lo 和 hi
+func (query *Query) Between(field string, lo, hi interface{}) *Query {
+	return query.GreaterThanOrEqual(field, lo).LessThanOrEqual(field, hi)
+}
+
+func (query *Query) CreatedAfter(t time.Time) *Query {
+	return query.GreaterThan("createdAt", t)
+}
+func (query *Query) CreatedBefore(t time.Time) *Query {
+	return query.LessThan("createdAt", t)
 }
-func (query *Query) GreaterThanOrEqual(field string, value float64) *Query {
-	query.where[field] = map[string]interface{}{"$gte": value}
+func (query *Query) UpdatedAfter(t time.Time) *Query {
+	return query.GreaterThan("updatedAt", t)
+}
+func (query *Query) UpdatedBefore(t time.Time) *Query {
+	return query.LessThan("updatedAt", t)
+}
+
+// 设置 StartWith, EndWith 及 Contains 是否区分大小写, 默认不区分
+// 只影响之后添加的条件
+func (query *Query) CaseSensitive(value bool) *Query {
+	query.caseSensitive = value
 	return query
 }
 
+// 文本匹配会自动转义正则表达式特殊字符
 func (query *Query) StartWith(field string, value string) *Query {
-	query.where[field] = map[string]interface{}{"$regex": fmt.Sprintf("/^%s/i", value)}
-	return query
+	return query.Regex(field, "^"+regexp.QuoteMeta(value), query.textMatchFlags())
 }
 func (query *Query) EndWith(field string, value string) *Query {
-	query.where[field] = map[string]interface{}{"$regex": fmt.Sprintf("/%s$/i", value)}
-	return query
+	return query.Regex(field, regexp.QuoteMeta(value)+"$", query.textMatchFlags())
 }
 func (query *Query) Contains(field string, value string) *Query {
-	query.where[field] = map[string]interface{}{"$regex": fmt.Sprintf("/%s/i", value)}
-	return query
+	return query.Regex(field, regexp.QuoteMeta(value), query.textMatchFlags())
+}
+
+// 使用正则表达式匹配
+// flags 可为 i, m, s, x 的组合
+func (query *Query) Regex(field string, pattern string, flags string) *Query {
+	if err := validateRegex(pattern, flags); err != nil {
+		query.err = &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("invalid regex for field '%s': %v", field, err.Error())}
+		return query
+	}
+	return query.addCondition(field, "$regex", fmt.Sprintf("/%s/%s", escapeRegexSlash(pattern), flags))
+}
+
+func (query *Query) textMatchFlags() string {
+	if query.caseSensitive {
+		return ""
+	}
+	return "i"
 }
 
 func (query *Query) Match(field string, value interface{}) *Query {
-	query.where[field] = map[string]interface{}{"$elemMatch": value}
-	return query
+	return query.addCondition(field, "$elemMatch", value)
 }
 
 func (query *Query) Exists(field string, exist bool) *Query {
-	query.where[field] = map[string]interface{}{"$exists": exist}
-	return query
+	return query.addCondition(field, "$exists", exist)
 }
 
 func (query *Query) Count(value bool) *Query {
@@ -90,18 +134,96 @@ func (query *Query) IncludeWithFields(field string, includeFields ...string) *Qu
 }
 
 func (query *Query) In(field string, value []interface{}) *Query {
-	query.where[field] = map[string]interface{}{"$in": value}
-	return query
+	return query.addCondition(field, "$in", value)
 }
 func (query *Query) NotIn(field string, value []interface{}) *Query {
-	query.where[field] = map[string]interface{}{"$nin": value}
-	return query
+	return query.addCondition(field, "$nin", value)
 }
 func (query *Query) MatchAll(field string, value []interface{}) *Query {
-	query.where[field] = map[string]interface{}{"$all": value}
+	return query.addCondition(field, "$all", value)
+}
+
+// 数组字段的长度等于 size
+func (query *Query) SizeEqual(field string, size int) *Query {
+	return query.addCondition(field, "$size", size)
+}
+
+// 字段值为指定类型, 如 "string", "number", "array"
+func (query *Query) TypeIs(field string, typ string) *Query {
+	return query.addCondition(field, "$type", typ)
+}
+
+// 字段值除以 divisor 的余数等于 remainder
+func (query *Query) Mod(field string, divisor int, remainder int) *Query {
+	if divisor == 0 {
+		query.err = &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("mod divisor of field '%s' cannot be 0", field)}
+		return query
+	}
+	return query.addCondition(field, "$mod", []interface{}{divisor, remainder})
+}
+
+// 对字段的条件取反, 条件在 fn 中设置, 如:
+//
+//	query.Not("age", func(q *Query) { q.GreaterThan("age", 18) })
+func (query *Query) Not(field string, fn func(*Query)) *Query {
+	sub := &Query{app: query.app, ResourceName: query.ResourceName, where: make(map[string]interface{})}
+	fn(sub)
+	if sub.err != nil {
+		query.err = sub.err
+		return query
+	}
+
+	cond, ok := sub.where[field].(map[string]interface{})
+	if !ok || !isOperatorMap(cond) {
+		query.err = &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("Not on field '%s' needs at least one operator constraint on the same field", field)}
+		return query
+	}
+	return query.addCondition(field, "$not", cond)
+}
+
+// 数组字段指定位置的元素等于 value, idx 从0开始
+func (query *Query) ElementAt(field string, idx int, value interface{}) *Query {
+	return query.Equal(fmt.Sprintf("%s.%d", field, idx), value)
+}
+
+// 直接设置字段的操作符条件, 与已有条件合并, 如:
+//
+//	query.Where("tags", map[string]interface{}{"$size": 2})
+func (query *Query) Where(field string, conditions map[string]interface{}) *Query {
+	for op, value := range conditions {
+		if !strings.HasPrefix(op, "$") {
+			query.err = &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("invalid operator '%s' on field '%s'", op, field)}
+			return query
+		}
+		query.addCondition(field, op, value)
+	}
 	return query
 }
 
+// 字段指向的数据满足子查询, 如作者在指定城市的文章
+func (query *Query) MatchesQuery(field string, subQuery *Query) *Query {
+	return query.addSubQuery(field, "$inQuery", subQuery, func(q map[string]interface{}) interface{} { return q })
+}
+
+// 字段指向的数据不满足子查询
+func (query *Query) DoesNotMatchQuery(field string, subQuery *Query) *Query {
+	return query.addSubQuery(field, "$notInQuery", subQuery, func(q map[string]interface{}) interface{} { return q })
+}
+
+// 字段值在子查询结果的 key 字段值中
+func (query *Query) MatchesKeyInQuery(field string, key string, subQuery *Query) *Query {
+	return query.addSubQuery(field, "$select", subQuery, func(q map[string]interface{}) interface{} {
+		return map[string]interface{}{"query": q, "key": key}
+	})
+}
+
+// 字段值不在子查询结果的 key 字段值中
+func (query *Query) DoesNotMatchKeyInQuery(field string, key string, subQuery *Query) *Query {
+	return query.addSubQuery(field, "$dontSelect", subQuery, func(q map[string]interface{}) interface{} {
+		return map[string]interface{}{"query": q, "key": key}
+	})
+}
+
 func (query *Query) OrderBy(field string) *Query {
 	query.order = append(query.order, field)
 	return query
@@ -119,9 +241,16 @@ func (query *Query) Select(fields ...string) *Query {
 
 func (query *Query) GetObject(objectId string) (Object, *APIError) {
 	var result Object
+	if query.err != nil {
+		return result, query.err
+	}
 
-	url := fmt.Sprintf("%s/resources/%s/%s?%s", query.app.baseURL, query.ResourceName, objectId, query.getQueryString())
-	m, err := query.app.sendGetRequest(url)
+	search, err := query.getQueryString()
+	if err != nil {
+		return result, err
+	}
+	url := fmt.Sprintf("%s/resources/%s/%s?%s", query.app.baseURL, query.ResourceName, objectId, search)
+	m, err := query.get(url, objectId)
 	if err != nil {
 		return result, err
 	}
@@ -134,16 +263,30 @@ func (query *Query) GetObject(objectId string) (Object, *APIError) {
 // 返回 数据列表， 总数 及出错信息
 func (query *Query) Find() ([]Object, int, *APIError) {
 	var result []Object
-	var count int64 = 0
+	if query.err != nil {
+		return result, 0, query.err
+	}
 
-	url := fmt.Sprintf("%s/resources/%s?%s", query.app.baseURL, query.ResourceName, query.getQueryString())
-	fmt.Println("url-->:", url)
+	search, err := query.getQueryString()
+	if err != nil {
+		return result, 0, err
+	}
+	url := fmt.Sprintf("%s/resources/%s?%s", query.app.baseURL, query.ResourceName, search)
 
-	m, err := query.app.sendGetRequest(url)
+	m, err := query.get(url, "")
 	if err != nil {
 		return result, 0, err
 	}
 
+	result, count := query.parseResults(m)
+	return result, count, nil
+}
+
+// 由查询结果创建数据列表及总数
+func (query *Query) parseResults(m map[string]interface{}) ([]Object, int) {
+	var result []Object
+	var count int64 = 0
+
 	if results, ok := m["results"].([]interface{}); ok {
 		for _, c := range results {
 			obj := query.app.NewObjectWithData(query.ResourceName, c.(map[string]interface{}))
@@ -155,16 +298,350 @@ func (query *Query) Find() ([]Object, int, *APIError) {
 		count = int64(c)
 	}
 
-	return result, int(count), nil
+	return result, int(count)
+}
+
+// 返回第一条匹配的数据
+// 没有数据时返回的错误 IsObjectNotFound() 为 true
+func (query *Query) First() (*Object, *APIError) {
+	q := query.clone()
+	q._take = 1
+	q._count = false
+
+	results, _, err := q.Find()
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, &APIError{Code: ERROR_CODE_OBJECT_NOT_FOUND, Error: fmt.Sprintf("no object found in '%s'", query.ResourceName)}
+	}
+
+	return &results[0], nil
+}
+
+// 只返回匹配的数量, 不返回数据
+func (query *Query) CountOnly() (int, *APIError) {
+	q := query.clone()
+	q._take = 0
+	q._count = true
+
+	_, count, err := q.Find()
+	return count, err
+}
+
+// 是否存在匹配的数据
+func (query *Query) Any() (bool, *APIError) {
+	q := query.clone()
+	q._skip = 0
+	q._take = 1
+	q._count = false
+	q.field = []string{"objectId"}
+	q.include = nil
+
+	results, _, err := q.Find()
+	if err != nil {
+		return false, err
+	}
+	return len(results) > 0, nil
+}
+
+// 返回字段的所有不同值, 只考虑 where 条件
+func (query *Query) Distinct(field string) ([]interface{}, *APIError) {
+	if query.err != nil {
+		return nil, query.err
+	}
+
+	where, err := query.encodedWhere()
+	if err != nil {
+		return nil, err
+	}
+	search := "field=" + url.QueryEscape(field) + "&where=" + where
+
+	m, err := query.send("GET", fmt.Sprintf("%s/distinct/%s?%s", query.app.baseURL, query.ResourceName, search), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	results, _ := m["results"].([]interface{})
+	return results, nil
+}
+
+// 返回字段的所有不同字符串值, 忽略非字符串的值
+func (query *Query) DistinctStrings(field string) ([]string, *APIError) {
+	values, err := query.Distinct(field)
+	if err != nil {
+		return nil, err
+	}
+	return interfacesToStrings(values), nil
+}
+
+// 返回字段的所有不同数字值, 忽略非数字的值
+func (query *Query) DistinctFloat64s(field string) ([]float64, *APIError) {
+	var result []float64
+	values, err := query.Distinct(field)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		if f, ok := v.(float64); ok {
+			result = append(result, f)
+		}
+	}
+	return result, nil
+}
+
+// 复制查询, 修改复制后的查询不会影响原查询
+func (query *Query) Clone() *Query {
+	q := query.clone()
+	q.debug = new(queryDebugRecorder)
+	return q
+}
+
+// 返回编码后的查询字符串, 与发送到服务器的一致
+// where 无法编码时返回空字符串
+func (query *Query) String() string {
+	search, _ := query.getQueryString()
+	return search
+}
+
+// 查询的 JSON 格式, 用于保存查询
+type queryJSON struct {
+	ResourceName  string                 `json:"resourceName"`
+	Where         map[string]interface{} `json:"where,omitempty"`
+	Order         []string               `json:"order,omitempty"`
+	Select        []string               `json:"select,omitempty"`
+	Include       []string               `json:"include,omitempty"`
+	Skip          int                    `json:"skip,omitempty"`
+	Take          int                    `json:"take"`
+	Count         bool                   `json:"count,omitempty"`
+	CaseSensitive bool                   `json:"caseSensitive,omitempty"`
+	Highlight     bool                   `json:"highlight,omitempty"`
+}
+
+func (query *Query) MarshalJSON() ([]byte, error) {
+	if query.err != nil {
+		return nil, errors.New(query.err.Error)
+	}
+
+	return json.Marshal(queryJSON{
+		ResourceName:  query.ResourceName,
+		Where:         query.where,
+		Order:         query.order,
+		Select:        query.field,
+		Include:       query.include,
+		Skip:          query._skip,
+		Take:          query._take,
+		Count:         query._count,
+		CaseSensitive: query.caseSensitive,
+		Highlight:     query.highlight,
+	})
+}
+
+// 解析 JSON 格式的查询
+// 需解析到由 App.NewQuery 创建的查询中, 否则无法执行查询
+func (query *Query) UnmarshalJSON(data []byte) error {
+	var q queryJSON
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&q); err != nil {
+		return err
+	}
+
+	query.ResourceName = q.ResourceName
+	query.where = q.Where
+	if query.where == nil {
+		query.where = make(map[string]interface{})
+	}
+	query.order = q.Order
+	query.field = q.Select
+	query.include = q.Include
+	query._skip = q.Skip
+	query._take = q.Take
+	query._count = q.Count
+	query.caseSensitive = q.CaseSensitive
+	query.highlight = q.Highlight
+	query.err = nil
+
+	return nil
+}
+
+// 复制查询, 条件等会被深拷贝
+func (query *Query) clone() *Query {
+	q := *query
+	q.where = copyValue(query.where).(map[string]interface{})
+	q.order = append([]string(nil), query.order...)
+	q.field = append([]string(nil), query.field...)
+	q.include = append([]string(nil), query.include...)
+	return &q
+}
+
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[k] = copyValue(item)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = copyValue(item)
+		}
+		return list
+	}
+	return value
+}
+
+// 添加字段的操作符条件
+// 同一字段的多个条件会合并到同一个map中, 如 {"$gt": 18, "$lt": 65}
+func (query *Query) addCondition(field string, op string, value interface{}) *Query {
+	existing, ok := query.where[field]
+	if !ok {
+		query.where[field] = map[string]interface{}{op: value}
+		return query
+	}
+
+	cond, ok := existing.(map[string]interface{})
+	if !ok || !isOperatorMap(cond) {
+		query.err = &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("cannot combine Equal with '%s' constraint on field '%s'", op, field)}
+		return query
+	}
+	cond[op] = value
+
+	return query
+}
+
+func (query *Query) addSubQuery(field string, op string, subQuery *Query, wrap func(map[string]interface{}) interface{}) *Query {
+	if subQuery.err != nil {
+		query.err = subQuery.err
+		return query
+	}
+	q := map[string]interface{}{
+		"resourceName": subQuery.ResourceName,
+		"where":        copyValue(subQuery.where),
+	}
+	return query.addCondition(field, op, wrap(q))
+}
+
+func (query *Query) addComparison(field string, op string, value interface{}) *Query {
+	v, err := encodeComparable(value)
+	if err != nil {
+		query.err = &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("invalid '%s' value for field '%s': %v", op, field, err.Error())}
+		return query
+	}
+	return query.addCondition(field, op, v)
+}
+
+// 转换比较条件的值
+func encodeComparable(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return encodeDate(v), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, string, json.Number:
+		return v, nil
+	}
+	return nil, fmt.Errorf("unsupported type %T", value)
+}
+
+// 服务器的 Date 类型
+func encodeDate(t time.Time) map[string]interface{} {
+	return map[string]interface{}{"__type": "Date", "iso": t.UTC().Format(time.RFC3339Nano)}
+}
+
+// 发送前检查正则表达式及标志是否有效
+// 只检查 flags, 空表达式, 结尾的转义符及括号是否配对, 其余语法由服务端校验
+// 服务端支持 go 不支持的语法, 如 (?!...), 后行断言及反向引用
+func validateRegex(pattern string, flags string) error {
+	for i, f := range flags {
+		if !strings.ContainsRune("imsx", f) || strings.ContainsRune(flags[:i], f) {
+			return fmt.Errorf("invalid flag '%c'", f)
+		}
+	}
+	if pattern == "" {
+		return fmt.Errorf("empty pattern")
+	}
+	// 结尾未转义的 `\` 会转义 /pattern/flags 的结束符
+	trailing := len(pattern) - len(strings.TrimRight(pattern, "\\"))
+	if trailing%2 == 1 {
+		return fmt.Errorf("trailing backslash in pattern")
+	}
+	return checkRegexBrackets(pattern)
 }
 
-func (query *Query) getQueryString() string {
+// 检查 `()` 及 `[]` 是否配对, 忽略转义的括号及字符类中的括号
+// 字符类开头的 `]` 为普通字符, 如 []a] 及 [^]a]
+func checkRegexBrackets(pattern string) error {
+	depth := 0
+	class := -1 // 当前字符类内容的起始位置, -1 表示不在字符类中
+	escaped := false
+	for i, c := range pattern {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case class >= 0:
+			if c == '^' && i == class {
+				class++
+			} else if c == ']' && i > class {
+				class = -1
+			}
+		case c == '[':
+			class = i + 1
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth == 0 {
+				return fmt.Errorf("unmatched ')' in pattern")
+			}
+			depth--
+		}
+	}
+	if class >= 0 {
+		return fmt.Errorf("missing closing ']' in pattern")
+	}
+	if depth > 0 {
+		return fmt.Errorf("missing closing ')' in pattern")
+	}
+	return nil
+}
+
+// 转义未转义的 `/`, 避免截断 /pattern/flags 格式
+func escapeRegexSlash(pattern string) string {
+	var buf bytes.Buffer
+	escaped := false
+	for _, c := range pattern {
+		if c == '/' && !escaped {
+			buf.WriteRune('\\')
+		}
+		escaped = c == '\\' && !escaped
+		buf.WriteRune(c)
+	}
+	return buf.String()
+}
+
+// 判断是否为操作符条件, 即所有key都以 `$` 开头
+func isOperatorMap(cond map[string]interface{}) bool {
+	if len(cond) == 0 {
+		return false
+	}
+	for k := range cond {
+		if !strings.HasPrefix(k, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+// where 无法编码为JSON时返回错误, 避免不带条件发送查询
+func (query *Query) getQueryString() (string, *APIError) {
 
 	search := "_=_"
 	if query._count {
 		search += "&count=1"
 	}
-	if len(query.order) > 0 {
+	if len(query.order) > 0 && !query.hasNear() {
 		search += ("&order=" + strings.Join(query.order, ","))
 	}
 	if len(query.field) > 0 {
@@ -179,11 +656,23 @@ func (query *Query) getQueryString() string {
 	if len(query.include) > 0 {
 		search += ("&include=" + strings.Join(query.include, ","))
 	}
+	if query.highlight {
+		search += "&highlight=1"
+	}
 
-	if b, err := json.Marshal(query.where); err == nil {
-		where := url.QueryEscape(string(b))
-		search += "&where=" + where
+	where, err := query.encodedWhere()
+	if err != nil {
+		return "", err
 	}
+	search += "&where=" + where
 
-	return search
+	return search, nil
+}
+
+func (query *Query) encodedWhere() (string, *APIError) {
+	b, err := json.Marshal(query.where)
+	if err != nil {
+		return "", &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("cannot encode where:%v", err.Error())}
+	}
+	return url.QueryEscape(string(b)), nil
 }
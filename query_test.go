@@ -0,0 +1,43 @@
+package skynology_test
+
+import (
+	"testing"
+
+	sky "github.com/skynology/go-sdk"
+)
+
+func TestRegexValidation(t *testing.T) {
+	tests := []struct {
+		pattern string
+		flags   string
+		valid   bool
+	}{
+		{`^a(b|c)+$`, "i", true},
+		{`[()]`, "", true},
+		{`[]a]`, "", true},
+		{`[^]a]`, "", true},
+		{`\(\[`, "", true},
+		{`(?<=a)b\1`, "", true},
+		{``, "", false},
+		{`a(b`, "", false},
+		{`a)b`, "", false},
+		{`[ab`, "", false},
+		{`[]`, "", false},
+		{`a\`, "", false},
+		{`a`, "g", false},
+	}
+
+	for _, tt := range tests {
+		handler := &recordingHandler{response: map[string]interface{}{"results": []interface{}{}}}
+		app := sky.NewApp("app", "key")
+		app.SetRequestHandler(handler)
+
+		_, _, err := app.NewQuery("Post").Regex("title", tt.pattern, tt.flags).Find()
+		if tt.valid && err != nil {
+			t.Errorf("%q: unexpected error %v", tt.pattern, err.Error)
+		}
+		if !tt.valid && (err == nil || len(handler.requests) != 0) {
+			t.Errorf("%q: should fail before sending", tt.pattern)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package skynology
+
+// 保存角色
+// 新建角色时必须设置角色名
+func (role *Role) Save() (bool, *APIError) {
+	if role.ObjectId == "" && role.Name == "" {
+		return false, &APIError{Code: ERROR_CODE_CLIENT, Error: "role name is required"}
+	}
+
+	ok, err := role.Object.Save()
+	if err != nil {
+		return ok, err
+	}
+
+	role.initData(role.data)
+	return true, nil
+}
+
+func (role *Role) Delete() (bool, *APIError) {
+	ok, err := role.Object.Delete()
+	if err != nil {
+		return ok, err
+	}
+
+	role.clear()
+	return true, nil
+}
+
+// 添加用户到角色
+func (role *Role) AddUser(userIds ...string) *Role {
+	role.AddUniqueValueToArrayFromList("users", stringsToInterfaces(userIds))
+	return role
+}
+
+// 从角色中移除用户
+func (role *Role) RemoveUser(userIds ...string) *Role {
+	role.RemoveValueFromArrayFromList("users", stringsToInterfaces(userIds))
+	return role
+}
+
+// 添加子角色, 子角色中的用户将继承当前角色的权限
+func (role *Role) AddChildRole(roleNames ...string) *Role {
+	role.AddUniqueValueToArrayFromList("roles", stringsToInterfaces(roleNames))
+	return role
+}
+
+// 移除子角色
+func (role *Role) RemoveChildRole(roleNames ...string) *Role {
+	role.RemoveValueFromArrayFromList("roles", stringsToInterfaces(roleNames))
+	return role
+}
+
+// 返回角色中的用户objectId
+func (role *Role) Users() []string {
+	return interfacesToStrings(role.GetArray("users"))
+}
+
+// 返回子角色名
+func (role *Role) ChildRoles() []string {
+	return interfacesToStrings(role.GetArray("roles"))
+}
+
+// 根据角色名获取角色
+func (app *App) GetRoleByName(name string) (*Role, *APIError) {
+	results, _, err := app.NewQuery("_Role").Equal("name", name).Take(1).Find()
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, &APIError{Code: ERROR_CODE_CLIENT, Error: "role not found"}
+	}
+
+	return app.NewRoleWithData(results[0].Map()), nil
+}
+
+// 返回用户所属的全部角色名, 包括通过子角色继承得到的角色
+// 返回结果可直接传给 Object.CheckACL
+func (user *User) Roles() ([]string, *APIError) {
+	var result []string
+	seen := map[string]bool{}
+
+	query := user.app.NewQuery("_Role").MatchAll("users", []interface{}{user.ObjectId})
+	names, err := findRoleNames(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// 不断查找以已知角色为子角色的父角色, 直到没有新角色为止
+	for len(names) > 0 {
+		var pending []interface{}
+		for _, name := range names {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			result = append(result, name)
+			pending = append(pending, name)
+		}
+		if len(pending) == 0 {
+			break
+		}
+
+		query = user.app.NewQuery("_Role").In("roles", pending)
+		names, err = findRoleNames(query)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (role *Role) initData(data map[string]interface{}) {
+	role.Object.initData(data)
+	if v, ok := data["name"].(string); ok {
+		role.Name = v
+	}
+}
+
+func (role *Role) clear() {
+	role.Object.clear()
+	role.Name = ""
+}
+
+// 分页取出所有匹配的角色名
+func findRoleNames(query *Query) ([]string, *APIError) {
+	var names []string
+	query.Select("name").Take(100)
+
+	for skip := 0; ; skip += 100 {
+		results, _, err := query.Skip(skip).Find()
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range results {
+			if name := obj.GetString("name"); name != "" {
+				names = append(names, name)
+			}
+		}
+		if len(results) < 100 {
+			break
+		}
+	}
+
+	return names, nil
+}
+
+func stringsToInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
+
+func interfacesToStrings(values []interface{}) []string {
+	var result []string
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
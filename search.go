@@ -0,0 +1,68 @@
+package skynology
+
+import "strings"
+
+// 全文搜索选项
+type SearchOptions struct {
+	// 分词语言, 如 "en", "zh", 为空时使用服务器默认设置
+	Language string
+	// 是否区分大小写
+	CaseSensitive bool
+	// 是否按相关度由高到低排序, 优先于 OrderBy 的设置
+	OrderByScore bool
+	// 是否返回高亮片段
+	Highlight bool
+}
+
+// 全文搜索
+// 返回的数据可通过 Object.SearchScore 获取相关度, Object.Highlights 获取高亮片段
+func (query *Query) Search(text string, options *SearchOptions) *Query {
+	if strings.TrimSpace(text) == "" {
+		query.err = &APIError{Code: ERROR_CODE_CLIENT, Error: "search text is empty"}
+		return query
+	}
+	if options == nil {
+		options = &SearchOptions{}
+	}
+
+	search := map[string]interface{}{"$search": text}
+	if options.Language != "" {
+		search["$language"] = options.Language
+	}
+	if options.CaseSensitive {
+		search["$caseSensitive"] = true
+	}
+	query.where["$text"] = search
+
+	if options.OrderByScore {
+		query.order = append([]string{"-$score"}, query.order...)
+	}
+	query.highlight = options.Highlight
+
+	return query
+}
+
+// 全文搜索的相关度
+func (obj *Object) SearchScore() float64 {
+	return obj.GetFloat64("_score")
+}
+
+// 全文搜索的高亮片段, key 为字段名
+// 服务器没有返回时为nil
+func (obj *Object) Highlights() map[string][]string {
+	m := obj.GetMap("_highlight")
+	if m == nil {
+		return nil
+	}
+
+	result := make(map[string][]string, len(m))
+	for field, v := range m {
+		switch snippets := v.(type) {
+		case string:
+			result[field] = []string{snippets}
+		case []interface{}:
+			result[field] = interfacesToStrings(snippets)
+		}
+	}
+	return result
+}
@@ -0,0 +1,231 @@
+package skynology
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// 数据变化类型
+type SyncChangeType string
+
+const (
+	// 新建或更新
+	SyncChangeUpsert SyncChangeType = "upsert"
+	// 删除
+	SyncChangeDelete SyncChangeType = "delete"
+)
+
+// 数据变化
+// 删除时 Object 为nil
+type SyncChange struct {
+	Type     SyncChangeType
+	ObjectId string
+	Object   *Object
+}
+
+// 默认的删除记录资源
+// 每条记录包含 resourceName 及 deletedId 字段, 分别为被删除数据的资源名及objectId
+const DEFAULT_DELETION_RESOURCE = "_Deletion"
+
+// 同步进度, 保存在 dataDir 中
+type syncCheckpoint struct {
+	Updated QueryCursor `json:"updated"`
+	Deleted QueryCursor `json:"deleted"`
+}
+
+// 轮询同步
+// 定期按 updatedAt 查询变化的数据, 并从删除记录资源中查询被删除的数据,
+// 对每个变化调用注册的回调, 进度保存在 dataDir 中, 重启后继续
+type SyncEngine struct {
+	app              *App
+	name             string
+	query            *Query
+	deletionResource string
+	interval         time.Duration
+	pageSize         int
+	callbacks        []func(SyncChange) error
+	checkpoint       syncCheckpoint
+	loaded           bool
+	mu               sync.Mutex
+}
+
+// 创建同步, name 用于区分保存的进度, 同一 app 下需唯一
+// query 的 where 条件会用于筛选需要同步的数据
+func (app *App) NewSyncEngine(name string, query *Query) *SyncEngine {
+	return &SyncEngine{
+		app:              app,
+		name:             name,
+		query:            query.clone(),
+		deletionResource: DEFAULT_DELETION_RESOURCE,
+		interval:         30 * time.Second,
+		pageSize:         defaultIteratorPageSize,
+	}
+}
+
+// 设置轮询间隔, 默认30秒
+func (e *SyncEngine) SetInterval(interval time.Duration) *SyncEngine {
+	if interval > 0 {
+		e.interval = interval
+	}
+	return e
+}
+
+// 设置每页数量, 默认100
+func (e *SyncEngine) SetPageSize(size int) *SyncEngine {
+	if size > 0 {
+		e.pageSize = size
+	}
+	return e
+}
+
+// 设置删除记录资源, 为空时不同步删除
+func (e *SyncEngine) SetDeletionResource(resourceName string) *SyncEngine {
+	e.deletionResource = resourceName
+	return e
+}
+
+// 注册回调, 回调返回错误时停止本次同步, 该变化会在下次同步时重试
+func (e *SyncEngine) OnChange(fn func(SyncChange) error) *SyncEngine {
+	e.callbacks = append(e.callbacks, fn)
+	return e
+}
+
+// 同步一次, 返回处理的变化数量
+func (e *SyncEngine) Sync() (int, *APIError) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.loaded {
+		if err := e.loadCheckpoint(); err != nil {
+			return 0, &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("load sync checkpoint error:%v", err.Error())}
+		}
+		e.loaded = true
+	}
+
+	count, err := e.syncUpdated()
+	if err == nil && e.deletionResource != "" {
+		var deleted int
+		deleted, err = e.syncDeleted()
+		count += deleted
+	}
+
+	if saveErr := e.saveCheckpoint(); saveErr != nil && err == nil {
+		err = &APIError{Code: ERROR_CODE_CLIENT, Error: fmt.Sprintf("save sync checkpoint error:%v", saveErr.Error())}
+	}
+	return count, err
+}
+
+// 按间隔不断同步, 直到 stop 被关闭
+// 同步出错时记录日志, 并在下个间隔重试
+func (e *SyncEngine) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := e.Sync(); err != nil {
+			log.Printf("sync '%s' failed, error:%v", e.name, err.String())
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// 清除进度, 下次同步将从头开始
+func (e *SyncEngine) Reset() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.checkpoint = syncCheckpoint{}
+	e.loaded = true
+	err := os.Remove(e.checkpointPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (e *SyncEngine) syncUpdated() (int, *APIError) {
+	count := 0
+	it := newQueryIterator(e.query, "updatedAt").PageSize(e.pageSize).StartAfter(e.checkpoint.Updated)
+	err := it.Each(func(obj *Object) error {
+		if err := e.emit(SyncChange{Type: SyncChangeUpsert, ObjectId: obj.ObjectId, Object: obj}); err != nil {
+			return err
+		}
+		e.checkpoint.Updated = it.Cursor()
+		count++
+		return nil
+	})
+	return count, err
+}
+
+func (e *SyncEngine) syncDeleted() (int, *APIError) {
+	count := 0
+	query := e.app.NewQuery(e.deletionResource).Equal("resourceName", e.query.ResourceName)
+	it := query.Iterator().PageSize(e.pageSize).StartAfter(e.checkpoint.Deleted)
+	err := it.Each(func(obj *Object) error {
+		id := obj.GetString("deletedId")
+		if id != "" {
+			if err := e.emit(SyncChange{Type: SyncChangeDelete, ObjectId: id}); err != nil {
+				return err
+			}
+			count++
+		}
+		e.checkpoint.Deleted = it.Cursor()
+		return nil
+	})
+	return count, err
+}
+
+func (e *SyncEngine) emit(change SyncChange) error {
+	for _, fn := range e.callbacks {
+		if err := fn(change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *SyncEngine) checkpointPath() string {
+	return fmt.Sprintf("%ssynology_sync_%s_%s", e.app.dataDir, e.app.ApplicationId, e.name)
+}
+
+func (e *SyncEngine) loadCheckpoint() error {
+	bin, err := ioutil.ReadFile(e.checkpointPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(bin) == 0 {
+		return nil
+	}
+	return json.Unmarshal(bin, &e.checkpoint)
+}
+
+// 先写入临时文件再重命名, 避免写入中断时进度文件损坏
+func (e *SyncEngine) saveCheckpoint() error {
+	bin, err := json.Marshal(e.checkpoint)
+	if err != nil {
+		return err
+	}
+	path := e.checkpointPath()
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, bin, os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package skynology
 
 import (
 	"fmt"
+	"sync"
 	"time"
 )
 
@@ -51,12 +52,19 @@ type APIError struct {
 	EnError     string `json:"error_en"`
 	Error       string `json:"error"`
 	Description string `json:"description,omitempty"`
+	// 服务器返回的http状态码, 由 DefaultHandler 设置, 本地错误时为0
+	StatusCode int `json:"-"`
 }
 
 func (a *APIError) String() string {
 	return fmt.Sprintf("code:%v, error:%s, description:%s", a.Code, a.Error, a.Description)
 }
 
+// 是否为 Query.First 等找不到数据时返回的错误
+func (a *APIError) IsObjectNotFound() bool {
+	return a != nil && a.Code == ERROR_CODE_OBJECT_NOT_FOUND
+}
+
 // Skynology GO SDK app
 type App struct {
 	ApplicationId  string
@@ -68,6 +76,12 @@ type App struct {
 	currentUser    *User
 	weixinParams   *weixinParams
 	handler        Handler
+	cache          QueryCache
+	maxURLLength   int
+
+	// 每个资源被清除缓存的次数, 后台请求期间有变化时不写入缓存
+	cacheMu          sync.Mutex
+	cacheGenerations map[string]uint64
 }
 
 // query function
@@ -81,6 +95,26 @@ type Query struct {
 	order        []string
 	field        []string
 	include      []string
+
+	// StartWith 等文本匹配是否区分大小写
+	caseSensitive bool
+
+	// 全文搜索时是否返回高亮片段
+	highlight bool
+
+	cachePolicy CachePolicy
+	cacheTTL    time.Duration
+	// CachePolicyCacheThenNetwork 时后台请求完成的回调
+	networkResult func([]Object, int, *APIError)
+
+	// 最后一次执行的调试信息
+	debug *queryDebugRecorder
+
+	// 没有 where 条件时是否允许 UpdateAll 及 DeleteAll
+	allowEmptyWhere bool
+
+	// 构造查询条件时产生的错误, 在执行查询时返回
+	err *APIError
 }
 
 // object type
@@ -107,6 +141,13 @@ type User struct {
 	Phone    string
 }
 
+// 角色, 对应 `_Role` 资源
+// users 字段保存用户objectId, roles 字段保存继承此角色权限的子角色名
+type Role struct {
+	Object
+	Name string
+}
+
 type File struct {
 	Object
 	Key    string